@@ -0,0 +1,61 @@
+// Package atomicfile writes files by way of a temporary file in the same directory, renamed into place once it is complete, so that a crash or a failed write (e.g. a full disk) never leaves a file partly written, or replaces a good one with a bad one.
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// File is a file being written under a temporary name beside its path. Close renames it into place.
+type File struct {
+	*os.File
+	path string
+	perm os.FileMode
+}
+
+// Create starts writing the file at path, which will get permission perm. Nothing at path is touched until Close.
+func Create(path string, perm os.FileMode) (*File, error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: f, path: path, perm: perm}, nil
+}
+
+// Close flushes the file to disk, closes it and renames it to its path, replacing any file already there. If any of that fails, the temporary file is removed and the file at its path (if any) is left as it was.
+func (f *File) Close() error {
+	tmp := f.Name()
+	err := f.Sync()
+	if err1 := f.File.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Chmod(tmp, f.perm)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// Abort closes and removes the temporary file, leaving the file at its path (if any) as it was.
+func (f *File) Abort() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// WriteFile is like ioutil.WriteFile, but writes data by way of a temporary file (see Create), so that path is never left partly written.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	f, err := Create(path, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		f.Abort()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,112 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// checkFile checks that the file at path holds want, and that dir holds nothing else (e.g. a temporary file left behind).
+func checkFile(t *testing.T, dir string, path string, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%v holds %q, want %q", filepath.Base(path), data, want)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if filepath.Join(dir, e.Name()) != path {
+			t.Errorf("left %v behind", e.Name())
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+	err := WriteFile(path, []byte("first"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteFile(path, []byte("second"), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, dir, path, "second")
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("permission %v, want %v", fi.Mode().Perm(), os.FileMode(0640))
+	}
+}
+
+func TestFailedWritePreservesOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+	err := os.WriteFile(path, []byte("original"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a write that fails part way, as on a full disk: the file can't be
+	// written to after some of it has been
+	f, err := Create(path, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write([]byte("partial"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.File.Close()
+	_, err = f.Write([]byte(" and the rest"))
+	if err == nil {
+		t.Fatal("write to a closed file succeeded")
+	}
+	err = f.Close()
+	if err == nil {
+		t.Error("Close of a failed file succeeded")
+	}
+	checkFile(t, dir, path, "original")
+
+	// a write given up on
+	f, err = Create(path, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("partial"))
+	err = f.Abort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, dir, path, "original")
+
+	// nothing is touched until the file is complete
+	f, err = Create(path, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("partial"))
+	data, _ := os.ReadFile(path)
+	if string(data) != "original" {
+		t.Errorf("while writing, %v holds %q", filepath.Base(path), data)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, dir, path, "partial")
+
+	err = WriteFile(filepath.Join(dir, "missing", "out.json"), []byte("lost"), 0644)
+	if err == nil {
+		t.Error("WriteFile to a missing directory succeeded")
+	}
+}
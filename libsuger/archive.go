@@ -0,0 +1,199 @@
+package libsuger
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// archiveKind returns "zip" or "tar.gz" for an archive path ending in .zip, or .tar.gz or .tgz, and an error for any other path.
+func archiveKind(path string) (string, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip", nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz", nil
+	}
+	msg := fmt.Sprintf("%s: archive name must end in .zip, .tar.gz or .tgz", path)
+	return "", errors.New(msg)
+}
+
+// ArchiveWriter writes files into a single .zip or .tar.gz archive. It is safe for concurrent use.
+type ArchiveWriter struct {
+	mu   sync.Mutex
+	f    *os.File
+	gz   *gzip.Writer
+	tw   *tar.Writer
+	zw   *zip.Writer
+	perm os.FileMode
+}
+
+// NewArchiveWriter creates the archive at path, which must not already exist. The kind of archive is chosen by the path's extension: .zip, or .tar.gz or .tgz. Files added to it get permission perm.
+func NewArchiveWriter(path string, perm os.FileMode) (*ArchiveWriter, error) {
+	kind, err := archiveKind(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return nil, err
+	}
+	aw := &ArchiveWriter{f: f, perm: perm}
+	if kind == "zip" {
+		aw.zw = zip.NewWriter(f)
+	} else {
+		aw.gz = gzip.NewWriter(f)
+		aw.tw = tar.NewWriter(aw.gz)
+	}
+	return aw, nil
+}
+
+// Add writes a file with the given name and contents to the archive.
+func (aw *ArchiveWriter) Add(name string, data []byte) error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	now := time.Now()
+	if aw.zw != nil {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now}
+		hdr.SetMode(aw.perm)
+		w, err := aw.zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(aw.perm),
+		Size:    int64(len(data)),
+		ModTime: now,
+	}
+	err := aw.tw.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = aw.tw.Write(data)
+	return err
+}
+
+// Close finishes the archive and closes its file.
+func (aw *ArchiveWriter) Close() error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	var err error
+	if aw.zw != nil {
+		err = aw.zw.Close()
+	} else {
+		err = aw.tw.Close()
+		if err == nil {
+			err = aw.gz.Close()
+		}
+	}
+	cerr := aw.f.Close()
+	if err != nil {
+		return err
+	}
+	return cerr
+}
+
+// ScrapeArchive is like the package-level ScrapeDir, but reads the files out of an archive written by ArchiveWriter (or any .zip, .tar.gz or .tgz archive). It uses the zero Scraper.
+func ScrapeArchive(path string) ([]*Title, error) {
+	var titles []*Title
+	var errs ScrapeErrors
+	collect := func(t *Title) error {
+		titles = append(titles, t)
+		return nil
+	}
+	skip := func(e *FileError) error {
+		errs = append(errs, e)
+		return nil
+	}
+	err := (&Scraper{}).ScrapeArchiveFunc(path, collect, skip)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return titles, errs
+	}
+	return titles, nil
+}
+
+// ScrapeArchiveFunc is like ScrapeDirFunc, but reads the files s wants out of the archive at path (see ScrapeArchive) rather than a directory. A FileError's Path is the archive's path joined to the file's name within it.
+func (s *Scraper) ScrapeArchiveFunc(path string, fn func(*Title) error, errFn func(*FileError) error) error {
+	kind, err := archiveKind(path)
+	if err != nil {
+		return err
+	}
+	if kind == "zip" {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		files := zr.File
+		next := func() (entry, error) {
+			for len(files) > 0 {
+				zf := files[0]
+				files = files[1:]
+				if !s.Wants(zf.FileInfo()) {
+					continue
+				}
+				load := func() ([]byte, error) {
+					rc, err := zf.Open()
+					if err != nil {
+						return nil, err
+					}
+					defer rc.Close()
+					return ioutil.ReadAll(rc)
+				}
+				return entry{path: path + "/" + zf.Name, load: load}, nil
+			}
+			return entry{}, io.EOF
+		}
+		return s.scrapeEntries(next, fn, errFn)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	next := func() (entry, error) {
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				return entry{}, err // io.EOF at the end
+			}
+			if hdr.Typeflag != tar.TypeReg || !s.Wants(hdr.FileInfo()) {
+				continue
+			}
+			// a tar archive can only be read in order, so the
+			// file is read here rather than by a worker
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return entry{}, err
+			}
+			load := func() ([]byte, error) {
+				return data, nil
+			}
+			return entry{path: path + "/" + hdr.Name, load: load}, nil
+		}
+	}
+	return s.scrapeEntries(next, fn, errFn)
+}
@@ -0,0 +1,62 @@
+package libsuger
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffConfig is the policy a Crawler uses to delay retrying a Job that failed. The delay before the nth retry is Initial * Multiplier^(n-1), capped at Max, and then randomly varied by up to +/- Jitter (a fraction of the delay, 0 to 1).
+type BackoffConfig struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// DefaultBackoff is the BackoffConfig used by a new Crawler: 2s, 4s, 8s, and so on up to 5 minutes, varied by 10%.
+var DefaultBackoff = BackoffConfig{
+	Initial:    2 * time.Second,
+	Max:        5 * time.Minute,
+	Multiplier: 2,
+	Jitter:     0.1,
+}
+
+// Delay returns how long to wait before the given attempt. Attempts are counted from zero, so the first attempt has no delay.
+func (b BackoffConfig) Delay(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	d := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt-1))
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		d = d + d*b.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// WithBackoff sets the BackoffConfig the Crawler uses when retrying a Job.
+func WithBackoff(b BackoffConfig) CrawlerOption {
+	return func(c *Crawler) error {
+		c.backoff = b
+		return nil
+	}
+}
+
+// sleep waits for d, returning early with ctx.Err() if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
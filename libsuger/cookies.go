@@ -0,0 +1,106 @@
+package libsuger
+
+import (
+	"encoding/json"
+	"github.com/colinhb/suger/internal/atomicfile"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// cookieFile is the format of the file written by WithCookieFile: the session cookies the site set, the URL they were set for (which the search form posts back to), and the search that session was on.
+type cookieFile struct {
+	URL     string
+	Cookies []*http.Cookie
+	Session *savedSession `json:",omitempty"`
+}
+
+// savedSession is the state of a session on the first page of a search's results, kept by WithCookieFile so that a later Crawler can carry on from it without loading the search form and searching again.
+type savedSession struct {
+	Fields url.Values // the page's magicFields
+	Types  []string   // see WithSearchTypes
+	Term   string     // see WithSearchTerm
+	Total  int        // see TotalResults
+}
+
+// WithCookieFile keeps the Crawler's session in the file at path, so that a later crawl (e.g. one resumed after stopping) can carry on the same session: the cookies the site set, and the hidden form fields of the first page of the search's results. The file is replaced whenever the Crawler has searched. If it exists, the cookies saved by an earlier run are loaded into the Crawler's cookie jar, and if the saved session was for the same search of the same site, the Crawler starts on it, going straight to a Job's page rather than loading the search form and searching again. If the saved session has expired, the Crawler starts a new one (see ErrSessionExpired).
+func WithCookieFile(path string) CrawlerOption {
+	return func(c *Crawler) error {
+		c.cookieFile = path
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var cf cookieFile
+		err = json.Unmarshal(data, &cf)
+		if err != nil {
+			return err
+		}
+		u, err := url.Parse(cf.URL)
+		if err != nil {
+			return err
+		}
+		c.Jar.SetCookies(u, cf.Cookies)
+		if cf.Session != nil {
+			c.saved = cf.Session
+			c.savedURL = cf.URL
+		}
+		return nil
+	}
+}
+
+// restoreSession starts the Crawler on the session loaded by WithCookieFile, if any, as if it had just searched, provided it was for the Crawler's site and search.
+func (c *Crawler) restoreSession() {
+	s := c.saved
+	c.saved = nil
+	if s == nil || len(s.Fields) == 0 {
+		return
+	}
+	saved, err := url.Parse(c.savedURL)
+	if err != nil {
+		return
+	}
+	start, err := url.Parse(c.start)
+	if err != nil || !strings.EqualFold(saved.Host, start.Host) {
+		return
+	}
+	if s.Term != c.searchTerm || strings.Join(s.Types, ",") != strings.Join(c.searchTypes, ",") {
+		c.logger.Debug("not restoring saved session for a different search", "file", c.cookieFile)
+		return
+	}
+	c.magicStrings = s.Fields
+	c.url = c.savedURL
+	c.page = 1
+	c.total = s.Total
+}
+
+// saveSession writes the cookies in the Crawler's jar for its URL, and the state of its session, to its cookie file (see WithCookieFile), if it has one. It is called just after a search, when the session is on the first page of results.
+func (c *Crawler) saveSession() error {
+	if c.cookieFile == "" {
+		return nil
+	}
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return err
+	}
+	cf := cookieFile{
+		URL:     c.url,
+		Cookies: c.Jar.Cookies(u),
+		Session: &savedSession{
+			Fields: c.magicStrings,
+			Types:  c.searchTypes,
+			Term:   c.searchTerm,
+			Total:  c.total,
+		},
+	}
+	data, err := json.Marshal(cf)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(c.cookieFile, data, 0600)
+}
@@ -0,0 +1,147 @@
+package libsuger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+// setSessionCookie makes site set a session cookie when its search form is loaded.
+func setSessionCookie(site *fakeSite) {
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if r.Method == "GET" {
+			http.SetCookie(w, &http.Cookie{Name: "ASP.NET_SessionId", Value: fmt.Sprint("session-", n), Path: "/"})
+		}
+		return false
+	}
+}
+
+func TestWithCookieFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	site, srv := newFakeSite(t, 25)
+	setSessionCookie(site)
+	c, err := NewCrawler(WithBaseURL(srv.URL), WithCookieFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.doInit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.doSearch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a later run loads the jar, and carries on the session
+	c, err = NewCrawler(WithBaseURL(srv.URL), WithCookieFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(srv.URL + searchPath)
+	cookies := c.Jar.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Name != "ASP.NET_SessionId" || cookies[0].Value != "session-1" {
+		t.Fatalf("loaded cookies %v, want the session cookie", cookies)
+	}
+	if total, ok := c.TotalResults(); !ok || total != 25 {
+		t.Errorf("restored session has %v results, want 25", total)
+	}
+	before := len(site.Requests())
+	j, _ := NewJob(19, 4)
+	results, err := c.CrawlResults(context.Background(), j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Errorf("got %v results, want 4", len(results))
+	}
+	var events []string
+	for _, r := range site.Requests()[before:] {
+		events = append(events, r.Event)
+		if r.Header.Get("Cookie") != "ASP.NET_SessionId=session-1" {
+			t.Errorf("%v %q sent cookies %q, want the saved session's", r.Method, r.Event, r.Header.Get("Cookie"))
+		}
+	}
+	want := []string{"Title$18", "Title$19", "Page$2", "Title$0", "Title$1"}
+	if !equalStrings(events, want) {
+		t.Errorf("resumed with requests %q, want %q (no search)", events, want)
+	}
+}
+
+func TestWithCookieFileExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	site, srv := newFakeSite(t, 25)
+	setSessionCookie(site)
+	c, err := NewCrawler(WithBaseURL(srv.URL), WithCookieFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CountResults(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the site has since forgotten the session
+	expired := false
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if r.Method == "GET" {
+			http.SetCookie(w, &http.Cookie{Name: "ASP.NET_SessionId", Value: "new-session", Path: "/"})
+			return false
+		}
+		if r.Header.Get("Cookie") == "ASP.NET_SessionId=session-1" {
+			expired = true
+			fmt.Fprint(w, fakeSearchForm)
+			return true
+		}
+		return false
+	}
+	before := len(site.Requests())
+	c, err = NewCrawler(WithBaseURL(srv.URL), WithCookieFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, _ := NewJob(21, 2)
+	results, err := c.CrawlResults(context.Background(), j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expired {
+		t.Error("the saved session wasn't tried")
+	}
+	if len(results) != 2 || results[0].Index != 21 {
+		t.Errorf("got %v results, want results 21 and 22", len(results))
+	}
+	var events []string
+	for _, r := range site.Requests()[before:] {
+		events = append(events, r.Method+" "+r.Event)
+	}
+	want := []string{"POST Page$2", "GET ", "POST Search", "POST Page$2", "POST Title$0", "POST Title$1"}
+	if !equalStrings(events, want) {
+		t.Errorf("requests %q, want %q", events, want)
+	}
+}
+
+func TestWithCookieFileOtherSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	_, srv := newFakeSite(t, 25)
+	c, err := NewCrawler(WithBaseURL(srv.URL), WithCookieFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CountResults(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opt := range []CrawlerOption{WithSearchTerm("SAMURAI"), WithSearchTypes("serial"), WithBaseURL("http://other.test")} {
+		c, err := NewCrawler(WithBaseURL(srv.URL), WithCookieFile(path), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.magicStrings != nil {
+			t.Errorf("restored a session saved for another search or site")
+		}
+	}
+}
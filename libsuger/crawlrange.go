@@ -0,0 +1,240 @@
+package libsuger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CrawlSummary counts what happened during a CrawlRange.
+type CrawlSummary struct {
+	Results  int            // results stored
+	Missing  int            // rows skipped with ErrNoSuchRow
+	Failed   int            // results given up on
+	Retries  int            // Jobs re-dispatched after failing
+	Requests int            // HTTP requests made
+	Elapsed  time.Duration  // wall time of the crawl
+	Failures []FailedResult // the results given up on, in the order they were
+}
+
+// RequestsPerSecond returns the average rate of requests over the crawl.
+func (s CrawlSummary) RequestsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Requests) / s.Elapsed.Seconds()
+}
+
+// Plan returns the Jobs CrawlRange would start its workers on for the same start, count and number of workers, without crawling anything.
+func Plan(start int, count int, workers int) ([]Job, error) {
+	j, err := NewJob(start, count)
+	if err != nil {
+		return nil, err
+	}
+	return j.Partition(workers)
+}
+
+// PlanChunks is like Plan, but for a crawl split into chunks of size results (see WithChunkSize) rather than one Job per worker.
+func PlanChunks(start int, count int, size int) ([]Job, error) {
+	j, err := NewJob(start, count)
+	if err != nil {
+		return nil, err
+	}
+	return j.PartitionBySize(size)
+}
+
+// CrawlRange crawls count results from start (as for NewJob), split between the given number of workers, and puts each Result in store. If the Crawler's chunk size is set (see WithChunkSize), the results are split into chunks of that size instead, and each worker takes the next chunk as it finishes one. Each worker uses a new Crawler made with opts. A Job that fails is retried (after the Crawler's backoff delay) from the result it failed on: by the same Crawler, on the same session, if it failed on its first attempt at a row, and otherwise by a fresh Crawler that searches again, until it has used up its attempts (see WithMaxAttempts), at which point the result it was failing on is given up and the rest of the Job carries on. A result whose error isn't Retryable (see CrawlError.Retryable) is given up at once. A row that fails with ErrNoSuchRow is skipped at once, and a ThrottleError pauses every worker: for as long as the site asked, or else for the backoff delay.
+//
+// CrawlRange returns when every result has been crawled or given up on, when ctx is done (returning ctx.Err()), or when store returns an error (returning it). Workers still running when it returns are cancelled. If any results were given up on, it returns an error saying how many. Whatever it returns, the CrawlSummary counts what was done. store is only ever called from the goroutine that called CrawlRange.
+func CrawlRange(ctx context.Context, start int, count int, workers int, store ResultStore, opts ...CrawlerOption) (CrawlSummary, error) {
+	var sum CrawlSummary
+	began := time.Now()
+	// cancelled on return, so that no worker is left blocked sending
+	// results that will never be received
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// a Crawler to check the options, and learn the settings that
+	// apply to the whole crawl (including the rate limiter and
+	// request count every Crawler shares)
+	c, err := NewCrawler(opts...)
+	if err != nil {
+		return sum, err
+	}
+	// report passes the Progress to the Crawler's progress function
+	// (see WithProgress), if it has one, when another so many results
+	// are done, or always if now
+	reported := 0 // results done when last reported
+	report := func(now bool) {
+		p := c.progress
+		if p == nil {
+			return
+		}
+		done := sum.Results + sum.Missing + sum.Failed
+		if !now && (p.every == 0 || done-reported < p.every) {
+			return
+		}
+		reported = done
+		p.fn(Progress{Done: done, Total: count, Requests: c.Requests(), Elapsed: time.Since(began)})
+	}
+	finish := func(err error) (CrawlSummary, error) {
+		report(true)
+		sum.Requests = c.Requests()
+		sum.Elapsed = time.Since(began)
+		return sum, err
+	}
+	if workers < 1 {
+		msg := fmt.Sprintf("the number of workers (%v) must be greater than zero.", workers)
+		return sum, errors.New(msg)
+	}
+	var parts []Job
+	if c.chunkSize > 0 {
+		parts, err = PlanChunks(start, count, c.chunkSize)
+	} else {
+		parts, err = Plan(start, count, workers)
+	}
+	if err != nil {
+		return sum, err
+	}
+
+	// make channels. Each Job is only ever in one place: queued, in
+	// jobs, or held by a worker, so sending a Job never blocks. A
+	// worker sending a result blocks until it is received, or ctx is
+	// done.
+	jobs := make(chan Job, len(parts))
+	results := make(chan Result, len(parts))
+
+	// start a Job for each worker; the rest wait their turn in queue
+	var queue []Job
+	for i := 0; i < len(parts); i++ {
+		parts[i].MaxAttempts = c.maxAttempts
+		if i < workers {
+			jobs <- parts[i]
+		} else {
+			queue = append(queue, parts[i])
+		}
+	}
+	// next starts the next Job in the queue, as a worker is now free
+	next := func() {
+		if len(queue) > 0 {
+			jobs <- queue[0]
+			queue = queue[1:]
+		}
+	}
+
+	remaining := len(parts)
+	throttles := 0 // ThrottleErrors since the last result
+	g := &gate{}
+	var tick <-chan time.Time // nil, and never ready, unless reporting progress at intervals
+	if c.progress != nil && c.progress.interval > 0 {
+		ticker := time.NewTicker(c.progress.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for remaining > 0 {
+		select {
+		case j := <-jobs:
+			c.logger.Debug("received job", "job", j)
+			if ctx.Err() != nil {
+				// cancelled; don't re-dispatch
+				remaining = remaining - 1
+				next()
+				continue
+			}
+			var te *ThrottleError
+			if errors.As(j.Error, &te) {
+				// pause every worker, not just this one
+				throttles = throttles + 1
+				d := te.RetryAfter
+				if d == 0 {
+					d = c.backoff.Delay(throttles)
+				}
+				c.logger.Warn("throttled; pausing all workers", "pause", d, "error", j.Error)
+				g.pause(d)
+			}
+			if errors.Is(j.Error, ErrNoSuchRow) {
+				// nothing to retry
+				c.logger.Info("skipping missing row", "error", j.Error)
+				sum.Missing = sum.Missing + 1
+				j = j.Skip()
+				report(false)
+			}
+			// retrying an error that isn't Retryable would only fail
+			// the same way again
+			var ce *CrawlError
+			permanent := errors.As(j.Error, &ce) && !ce.Retryable()
+			if j.Failed() || permanent {
+				c.logger.Warn("giving up on result", "attempts", j.Attempts, "retryable", !permanent, "error", j.Error)
+				sum.Failed = sum.Failed + 1
+				sum.Failures = append(sum.Failures, FailedResult{
+					Index: j.Start(),
+					Page:  j.page(c.perPage),
+					Row:   j.row(c.perPage),
+					Error: j.Error.Error(),
+				})
+				j = j.Skip()
+				report(false)
+			}
+			if j.IsDone() {
+				remaining = remaining - 1
+				c.logger.Debug("worker finished", "remaining", remaining)
+				next()
+				continue
+			}
+			if j.Error != nil {
+				c.logger.Warn("retrying after backoff", "attempt", j.Attempts+1, "error", j.Error)
+				sum.Retries = sum.Retries + 1
+				c.metrics.Retry()
+			}
+			// carry on with the Crawler that failed on a row, unless it
+			// has already failed twice running (its session may be bad)
+			w := j.crawler
+			if w == nil || j.Attempts > 1 {
+				w, err = NewCrawler(opts...)
+				if err != nil {
+					return finish(err)
+				}
+				w.gate = g
+				w.requests = c.requests
+				w.limiter = c.limiter
+			}
+			c.metrics.WorkerStarted()
+			go func(j Job) {
+				w.Crawl(ctx, j, results, jobs)
+				c.metrics.WorkerStopped()
+			}(j)
+		case r := <-results:
+			throttles = 0
+			err = store.Store(r)
+			if err != nil {
+				return finish(err)
+			}
+			sum.Results = sum.Results + 1
+			c.metrics.Result()
+			report(false)
+		case <-tick:
+			report(true)
+		}
+	}
+
+	// Every worker sends its results before its last Job, so any results
+	// not yet stored are waiting in the channel's buffer.
+	for len(results) > 0 {
+		err = store.Store(<-results)
+		if err != nil {
+			return finish(err)
+		}
+		sum.Results = sum.Results + 1
+		c.metrics.Result()
+	}
+	if ctx.Err() != nil {
+		return finish(ctx.Err())
+	}
+	if sum.Failed > 0 {
+		msg := fmt.Sprintf("gave up on %v results.", sum.Failed)
+		return finish(errors.New(msg))
+	}
+	return finish(nil)
+}
@@ -0,0 +1,102 @@
+package libsuger
+
+import (
+	"strings"
+)
+
+// Decision is the normalized outcome of a classification, parsed from the free text of Rating.Decision.
+type Decision int
+
+const (
+	DecisionUnknown  Decision = iota // empty, or wording not recognized
+	DecisionClean                    // "Passed Clean"
+	DecisionEdited                   // "Passed Clean (Edited)": passed as submitted, but the version submitted had been edited
+	DecisionCuts                     // "Passed With Cuts" or "Passed With Edits"
+	DecisionExempted                 // "Exempted" from classification
+	DecisionRefused                  // "Banned", or "Not for All Ratings" (see narDecisions)
+)
+
+var decisionNames = []string{
+	DecisionUnknown:  "Unknown",
+	DecisionClean:    "Clean",
+	DecisionEdited:   "Edited",
+	DecisionCuts:     "Cuts",
+	DecisionExempted: "Exempted",
+	DecisionRefused:  "Refused",
+}
+
+func (d Decision) String() string {
+	if d < 0 || int(d) >= len(decisionNames) {
+		return "Unknown"
+	}
+	return decisionNames[d]
+}
+
+// strictness orders Decisions from least to most restrictive, for choosing the strictest of several.
+var strictness = map[Decision]int{
+	DecisionUnknown:  0,
+	DecisionExempted: 1,
+	DecisionClean:    2,
+	DecisionEdited:   3,
+	DecisionCuts:     4,
+	DecisionRefused:  5,
+}
+
+// narDecisions are the ways the site words a refusal as "Not for All Ratings", lower-cased. It is shared by ParseDecision and legacyRating, which gives such a record the rating "NAR".
+var narDecisions = []string{
+	"nar",
+	"not for all ratings",
+	"not allowed for all ratings",
+}
+
+// normalizeDecision returns the text of a decision in lower case with runs of whitespace collapsed, for matching.
+func normalizeDecision(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// isNAR returns true if the decision s (normalized by normalizeDecision) is one of narDecisions.
+func isNAR(s string) bool {
+	for _, d := range narDecisions {
+		if s == d {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDecision normalizes the text of a decision. Matching ignores case and extra whitespace.
+func ParseDecision(s string) Decision {
+	s = normalizeDecision(s)
+	switch {
+	case s == "":
+		return DecisionUnknown
+	case s == "banned", isNAR(s):
+		return DecisionRefused
+	case s == "exempted":
+		return DecisionExempted
+	case strings.HasPrefix(s, "passed with cut"), strings.HasPrefix(s, "passed with edit"):
+		return DecisionCuts
+	case strings.HasPrefix(s, "passed clean"):
+		if strings.Contains(s, "edited") {
+			return DecisionEdited
+		}
+		return DecisionClean
+	}
+	return DecisionUnknown
+}
+
+// IsClean returns true if the title was passed clean, with no cuts or edits.
+func (r Rating) IsClean() bool {
+	return ParseDecision(r.Decision) == DecisionClean
+}
+
+// Cuts returns true if the title was passed only after being cut or edited (DecisionCuts or DecisionEdited).
+func (r Rating) Cuts() bool {
+	d := ParseDecision(r.Decision)
+	return d == DecisionCuts || d == DecisionEdited
+}
+
+// Refused returns true if the Decision means the title was refused classification (DecisionRefused), i.e. it is "Banned", or "Not for All Ratings" ("NAR").
+func (r Rating) Refused() bool {
+	return ParseDecision(r.Decision) == DecisionRefused
+}
@@ -0,0 +1,104 @@
+package libsuger
+
+import (
+	"testing"
+)
+
+func TestTitleRefused(t *testing.T) {
+	titles := []*Title{
+		{Name: "PASSED", Ratings: []Rating{{"Parental Guidance", "Passed Clean", ""}}},
+		{Name: "CUT", Ratings: []Rating{{"Restricted 21", "Passed With Cuts", ""}}},
+		{Name: "BANNED", Ratings: []Rating{{"", "Banned", ""}}},
+		{Name: "NAR", Ratings: []Rating{{"NAR", "Not for All Ratings", ""}}},
+		{Name: "NAR, OLDER WORDING", Ratings: []Rating{{"", "Not Allowed for All Ratings", ""}}},
+		{Name: "PASSED THEN BANNED", Ratings: []Rating{{"Matured Above 18", "Passed Clean", ""}, {"", "  BANNED ", ""}}},
+		{Name: "NO RATINGS"},
+	}
+	want := map[string]bool{
+		"BANNED":             true,
+		"NAR":                true,
+		"NAR, OLDER WORDING": true,
+		"PASSED THEN BANNED": true,
+	}
+	for _, title := range titles {
+		if got := title.Refused(); got != want[title.Name] {
+			t.Errorf("%q: Refused() = %v, want %v", title.Name, got, want[title.Name])
+		}
+	}
+}
+
+func TestLegacyRatingNAR(t *testing.T) {
+	// the rating NewTitleFromHTML gives a refusal, and ParseDecision,
+	// must agree on what a refusal is
+	for _, dec := range []string{"Not for All Ratings", "NOT FOR ALL  RATINGS", "NAR", "Not Allowed for All Ratings"} {
+		if got := legacyRating("", dec); got != "NAR" {
+			t.Errorf("legacyRating(%q) = %q, want NAR", dec, got)
+		}
+		if ParseDecision(dec) != DecisionRefused {
+			t.Errorf("ParseDecision(%q) = %v, want Refused", dec, ParseDecision(dec))
+		}
+	}
+	if got := legacyRating("RA", "Not for All Ratings"); got != "RA" {
+		t.Errorf("legacyRating kept %q, want RA", got)
+	}
+	if got := legacyRating("", "Passed Clean"); got != "" {
+		t.Errorf("legacyRating gave a passed title the rating %q", got)
+	}
+}
+
+func TestParseDecision(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Decision
+	}{
+		{"Passed Clean", DecisionClean},
+		{"  passed   CLEAN ", DecisionClean},
+		{"Passed\tClean\n", DecisionClean},
+		{"Passed Clean (Edited)", DecisionEdited},
+		{"Passed With Edits", DecisionCuts},
+		{"Passed with edit", DecisionCuts},
+		{"Passed With Cuts", DecisionCuts},
+		{"PASSED WITH CUT", DecisionCuts},
+		{"Exempted", DecisionExempted},
+		{"Banned", DecisionRefused},
+		{"Not for All Ratings", DecisionRefused},
+		{"", DecisionUnknown},
+		{"   ", DecisionUnknown},
+		{"Pending", DecisionUnknown},
+	}
+	for _, tt := range tests {
+		if got := ParseDecision(tt.s); got != tt.want {
+			t.Errorf("ParseDecision(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestRatingIsCleanCuts(t *testing.T) {
+	tests := []struct {
+		decision string
+		clean    bool
+		cuts     bool
+	}{
+		{"Passed Clean", true, false},
+		{"Passed With Edits", false, true},
+		{"Passed With Cuts", false, true},
+		{"Passed Clean (Edited)", false, true},
+		{"", false, false},
+		{"Banned", false, false},
+	}
+	for _, tt := range tests {
+		r := Rating{Rating: "Parental Guidance", Decision: tt.decision}
+		if r.IsClean() != tt.clean || r.Cuts() != tt.cuts {
+			t.Errorf("%q: IsClean() = %v, Cuts() = %v, want %v, %v", tt.decision, r.IsClean(), r.Cuts(), tt.clean, tt.cuts)
+		}
+	}
+}
+
+func TestDecisionString(t *testing.T) {
+	if s := DecisionCuts.String(); s != "Cuts" {
+		t.Errorf("DecisionCuts.String() = %q", s)
+	}
+	if s := Decision(99).String(); s != "Unknown" {
+		t.Errorf("Decision(99).String() = %q, want Unknown", s)
+	}
+}
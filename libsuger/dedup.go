@@ -0,0 +1,92 @@
+package libsuger
+
+// Key returns the key identifying the title's record: its ID if it has one, and otherwise its URL.
+func (t *Title) Key() string {
+	if t.ID != "" {
+		return t.ID
+	}
+	return t.URL
+}
+
+// Deduper drops duplicate Titles (those with the same Key), keeping the first of each. If Merge is true, ratings found only on a duplicate are added to the first Title, so no rating is lost; this means holding on to every Title added. The zero value is ready to use.
+type Deduper struct {
+	Merge   bool
+	Dropped int // number of duplicates seen
+	seen    map[string]*Title
+}
+
+// Add returns true if t is the first Title with its Key, and false (after merging its ratings, if Merge is set) if it is a duplicate.
+func (d *Deduper) Add(t *Title) bool {
+	if d.seen == nil {
+		d.seen = make(map[string]*Title)
+	}
+	first, ok := d.seen[t.Key()]
+	if !ok {
+		if d.Merge {
+			d.seen[t.Key()] = t
+		} else {
+			d.seen[t.Key()] = nil
+		}
+		return true
+	}
+	d.Dropped = d.Dropped + 1
+	if d.Merge {
+		first.mergeRatings(t)
+	}
+	return false
+}
+
+// mergeRatings adds to t any of other's Ratings that t doesn't already have.
+func (t *Title) mergeRatings(other *Title) {
+	have := make(map[Rating]bool)
+	for _, r := range t.Ratings {
+		have[r.key()] = true
+	}
+	for _, r := range other.Ratings {
+		if !have[r.key()] {
+			t.Ratings = append(t.Ratings, r)
+			have[r.key()] = true
+		}
+	}
+}
+
+// MergeTitles returns the titles in old updated with those in newer: a title in newer replaces the one in old with the same Key, in its place, and the rest of newer follow in their order. Of titles in newer with the same Key, the last wins. It also returns the number of titles replaced. Neither slice is modified.
+func MergeTitles(old []*Title, newer []*Title) ([]*Title, int) {
+	byKey := make(map[string]*Title)
+	for _, t := range newer {
+		byKey[t.Key()] = t
+	}
+	merged := make([]*Title, 0, len(old)+len(newer))
+	replaced := 0
+	used := make(map[string]bool)
+	for _, t := range old {
+		if n, ok := byKey[t.Key()]; ok {
+			if !used[t.Key()] {
+				merged = append(merged, n)
+				used[t.Key()] = true
+				replaced = replaced + 1
+			}
+			continue
+		}
+		merged = append(merged, t)
+	}
+	for _, t := range newer {
+		if !used[t.Key()] {
+			merged = append(merged, byKey[t.Key()])
+			used[t.Key()] = true
+		}
+	}
+	return merged, replaced
+}
+
+// Dedup returns titles with duplicates removed (see Deduper), and the number removed.
+func Dedup(titles []*Title, merge bool) ([]*Title, int) {
+	d := &Deduper{Merge: merge}
+	var unique []*Title
+	for _, t := range titles {
+		if d.Add(t) {
+			unique = append(unique, t)
+		}
+	}
+	return unique, d.Dropped
+}
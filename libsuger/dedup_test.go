@@ -0,0 +1,69 @@
+package libsuger
+
+import (
+	"testing"
+)
+
+func TestScrapeDedupMerge(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		// the same title, fetched twice (e.g. on a retry), the second
+		// time with a rating added since
+		"title-1-0.html": titlePage("ID1", "TWICE", Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}),
+		"title-1-1.html": titlePage("ID1", "TWICE",
+			Rating{Rating: "Parental Guidance", Decision: "Passed Clean"},
+			Rating{Rating: "Matured Above 18", Decision: "Passed With Cuts"}),
+		"title-1-2.html": titlePage("ID2", "ONCE", Rating{Rating: "General Viewing", Decision: "Passed Clean"}),
+	})
+	titles, err := ScrapeDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unique, dropped := Dedup(titles, true)
+	if dropped != 1 || len(unique) != 2 {
+		t.Fatalf("kept %v titles and dropped %v, want 2 and 1", len(unique), dropped)
+	}
+	twice := unique[0]
+	if twice.ID != "ID1" || len(twice.Ratings) != 2 || !twice.HasRating("Matured Above 18") {
+		t.Errorf("merged title is %v with ratings %v, want ID1 with both ratings", twice.ID, twice.Ratings)
+	}
+
+	// without merging, the first is kept as it was
+	titles, _ = ScrapeDir(dir)
+	unique, dropped = Dedup(titles, false)
+	if dropped != 1 || len(unique) != 2 || len(unique[0].Ratings) != 1 {
+		t.Errorf("without merging, kept %v titles (the first with %v ratings), dropped %v", len(unique), len(unique[0].Ratings), dropped)
+	}
+}
+
+func TestTitleKey(t *testing.T) {
+	withID := &Title{ID: "ID1", URL: "https://example.com/a"}
+	withoutID := &Title{URL: "https://example.com/b"}
+	if withID.Key() != "ID1" || withoutID.Key() != "https://example.com/b" {
+		t.Errorf("keys %q and %q", withID.Key(), withoutID.Key())
+	}
+	d := &Deduper{}
+	for _, title := range []*Title{withoutID, withID, {URL: "https://example.com/b"}, {ID: "ID1", URL: "elsewhere"}} {
+		d.Add(title)
+	}
+	if d.Dropped != 2 {
+		t.Errorf("dropped %v, want 2", d.Dropped)
+	}
+}
+
+func TestMergeTitles(t *testing.T) {
+	old := []*Title{{ID: "ID1", Name: "KEPT"}, {ID: "ID2", Name: "OLD NAME"}, {URL: "https://example.com/c", Name: "NO ID"}}
+	newer := []*Title{{ID: "ID3", Name: "ADDED"}, {ID: "ID2", Name: "NEWER NAME"}, {URL: "https://example.com/c", Name: "NO ID, NEWER"}, {ID: "ID2", Name: "NEWEST NAME"}}
+	merged, replaced := MergeTitles(old, newer)
+	var got []string
+	for _, title := range merged {
+		got = append(got, title.Name)
+	}
+	want := []string{"KEPT", "NEWEST NAME", "NO ID, NEWER", "ADDED"}
+	if !equalStrings(got, want) || replaced != 2 {
+		t.Errorf("merged %q, replacing %v, want %q, replacing 2", got, replaced, want)
+	}
+	if old[1].Name != "OLD NAME" || len(old) != 3 {
+		t.Error("MergeTitles modified old")
+	}
+}
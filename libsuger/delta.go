@@ -0,0 +1,33 @@
+package libsuger
+
+import (
+	"strings"
+)
+
+// DeltaSkip returns a skip predicate (see WithSkip) for crawling only what has changed since known was scraped: given the entries listed in the search result grid (see ListTitles), it passes over each listed row whose name is the Name of one of the known Titles, matched regardless of case and spacing, so that only new titles, and titles whose name has changed, are fetched. Rows not among entries are never passed over. It also returns the number of entries left to fetch.
+//
+// The grid gives no ID for a title, only its name and position, so a new title with the same name as a known one is passed over too. The predicate goes by position, so it assumes the grid's order doesn't change between listing the entries and crawling them: list them just before the crawl, as a title added to the database meanwhile shifts the rows after it.
+func DeltaSkip(entries []ListEntry, known []*Title) (func(page int, row int) bool, int) {
+	names := make(map[string]bool)
+	for _, t := range known {
+		names[deltaName(t.Name)] = true
+	}
+	type position struct {
+		page int
+		row  int
+	}
+	skip := make(map[position]bool)
+	for _, e := range entries {
+		if names[deltaName(e.Name)] {
+			skip[position{e.Page, e.Row}] = true
+		}
+	}
+	return func(page int, row int) bool {
+		return skip[position{page, row}]
+	}, len(entries) - len(skip)
+}
+
+// deltaName returns a title's name as compared by DeltaSkip: lower-cased, with runs of whitespace collapsed.
+func deltaName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,93 @@
+package libsuger
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDeltaSkip(t *testing.T) {
+	entries := []ListEntry{
+		{Name: "KNOWN", Page: 1, Row: 0},
+		{Name: "NEW", Page: 1, Row: 1},
+		{Name: "known  twice", Page: 1, Row: 2},
+		{Name: "RENAMED", Page: 2, Row: 0},
+	}
+	known := []*Title{
+		{Name: "KNOWN"},
+		{Name: " Known Twice"},
+		{Name: "OLD NAME"},
+		{Name: "GONE"},
+	}
+	skip, left := DeltaSkip(entries, known)
+	if left != 2 {
+		t.Errorf("%v entries left to fetch, want 2", left)
+	}
+	want := map[[2]int]bool{
+		{1, 0}: true,
+		{1, 1}: false,
+		{1, 2}: true,
+		{2, 0}: false,
+		{2, 1}: false, // not listed
+	}
+	for pos, skipped := range want {
+		if skip(pos[0], pos[1]) != skipped {
+			t.Errorf("skip(page %v, row %v) = %v, want %v", pos[0], pos[1], !skipped, skipped)
+		}
+	}
+}
+
+func TestDeltaCrawl(t *testing.T) {
+	site, srv := newFakeSite(t, 25)
+	// the reference scrape lacks results 3 and 21, and knows result 10
+	// by an older name
+	var known []*Title
+	for i := 1; i <= 25; i++ {
+		switch i {
+		case 3, 21:
+		case 10:
+			known = append(known, &Title{Name: "OLD NAME"})
+		default:
+			known = append(known, &Title{Name: fakeName(i)})
+		}
+	}
+	c, err := NewCrawler(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []ListEntry
+	err = c.ListTitles(context.Background(), 1, 0, func(e ListEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	skip, left := DeltaSkip(entries, known)
+	if left != 3 {
+		t.Errorf("%v titles left to fetch, want 3", left)
+	}
+
+	listed := len(site.Requests())
+	var results []Result
+	_, err = CrawlRange(context.Background(), 1, 25, 2, storeResults(&results), WithBaseURL(srv.URL), WithSkip(skip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[int]bool)
+	for _, r := range results {
+		got[r.Index] = true
+	}
+	if len(results) != 3 || !got[3] || !got[10] || !got[21] {
+		t.Errorf("fetched results %v, want 3, 10 and 21", got)
+	}
+	rows := 0
+	for _, r := range site.Requests()[listed:] {
+		if strings.HasPrefix(r.Event, "Title$") {
+			rows = rows + 1
+		}
+	}
+	if rows != 3 {
+		t.Errorf("requested %v rows, want 3", rows)
+	}
+}
@@ -0,0 +1,94 @@
+package libsuger
+
+import (
+	"sort"
+)
+
+// TitleDiff is the difference between two sets of Titles, such as the output of two scrapes, matched by Key. Each list is sorted by Key.
+type TitleDiff struct {
+	Added    []*Title      // in the new set only
+	Removed  []*Title      // in the old set only
+	Modified []TitleChange // in both, but changed
+}
+
+// TitleChange describes how a Title in both sets of a TitleDiff changed: its name (if that changed), and the ratings (rating and decision pairs) it gained and lost, as in a reclassification.
+type TitleChange struct {
+	Key            string
+	Name           string   // the new name
+	OldName        string   `json:",omitempty"` // the old name, if it changed
+	RatingsAdded   []Rating `json:",omitempty"`
+	RatingsRemoved []Rating `json:",omitempty"`
+}
+
+// DiffTitles returns the difference between the old and new sets of Titles. Within each set, only the first Title with a given Key counts (see Deduper).
+func DiffTitles(old []*Title, new []*Title) TitleDiff {
+	var d TitleDiff
+	oldByKey := make(map[string]*Title)
+	for _, t := range old {
+		if _, ok := oldByKey[t.Key()]; !ok {
+			oldByKey[t.Key()] = t
+		}
+	}
+	newByKey := make(map[string]*Title)
+	for _, t := range new {
+		if _, ok := newByKey[t.Key()]; ok {
+			continue
+		}
+		newByKey[t.Key()] = t
+		o, ok := oldByKey[t.Key()]
+		if !ok {
+			d.Added = append(d.Added, t)
+			continue
+		}
+		c, changed := diffTitle(o, t)
+		if changed {
+			d.Modified = append(d.Modified, c)
+		}
+	}
+	for key, t := range oldByKey {
+		if _, ok := newByKey[key]; !ok {
+			d.Removed = append(d.Removed, t)
+		}
+	}
+	byKey := func(titles []*Title) {
+		sort.Slice(titles, func(i, j int) bool {
+			return titles[i].Key() < titles[j].Key()
+		})
+	}
+	byKey(d.Added)
+	byKey(d.Removed)
+	sort.Slice(d.Modified, func(i, j int) bool {
+		return d.Modified[i].Key < d.Modified[j].Key
+	})
+	return d
+}
+
+// diffTitle returns the change from o to t, and whether there is any.
+func diffTitle(o *Title, t *Title) (TitleChange, bool) {
+	c := TitleChange{Key: t.Key(), Name: t.Name}
+	changed := false
+	if o.Name != t.Name {
+		c.OldName = o.Name
+		changed = true
+	}
+	had := make(map[Rating]bool)
+	for _, r := range o.Ratings {
+		had[r.key()] = true
+	}
+	has := make(map[Rating]bool)
+	for _, r := range t.Ratings {
+		if !had[r.key()] && !has[r.key()] {
+			c.RatingsAdded = append(c.RatingsAdded, r)
+			changed = true
+		}
+		has[r.key()] = true
+	}
+	for _, r := range o.Ratings {
+		if !has[r.key()] {
+			c.RatingsRemoved = append(c.RatingsRemoved, r)
+			has[r.key()] = true // report once
+			changed = true
+		}
+	}
+	return c, changed
+}
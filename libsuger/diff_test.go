@@ -0,0 +1,50 @@
+package libsuger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTitles(t *testing.T) {
+	pg := Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}
+	nc16 := Rating{Rating: "No Children Under 16", Decision: "Passed Clean"}
+	nc16Cuts := Rating{Rating: "No Children Under 16", Decision: "Passed With Cuts"}
+	old := []*Title{
+		{ID: "SAME", Name: "SAME", Ratings: []Rating{pg}},
+		{ID: "GONE", Name: "GONE", Ratings: []Rating{pg}},
+		{ID: "RECLASSIFIED", Name: "RECLASSIFIED", Ratings: []Rating{nc16Cuts}},
+		{ID: "RENAMED", Name: "OLD NAME", Ratings: []Rating{pg}},
+		{URL: "https://example.com/no-id", Name: "NO ID", Ratings: []Rating{pg}},
+		{ID: "ALT TEXT", Name: "ALT TEXT", Ratings: []Rating{pg}},
+	}
+	new := []*Title{
+		{ID: "NEW", Name: "NEW", Ratings: []Rating{nc16}},
+		{ID: "SAME", Name: "SAME", Ratings: []Rating{pg, pg}},
+		{ID: "RECLASSIFIED", Name: "RECLASSIFIED", Ratings: []Rating{nc16Cuts, pg}},
+		{ID: "RENAMED", Name: "NEW NAME", Ratings: []Rating{}},
+		{URL: "https://example.com/no-id", Name: "NO ID", Ratings: []Rating{pg}},
+		// only the rating's spelling on the page changed
+		{ID: "ALT TEXT", Name: "ALT TEXT", Ratings: []Rating{{Rating: "parental  guidance", Decision: "Passed Clean", RawRating: "parental  guidance"}}},
+		// a duplicate doesn't count
+		{ID: "NEW", Name: "NEW AGAIN"},
+	}
+	d := DiffTitles(old, new)
+	if len(d.Added) != 1 || d.Added[0].Name != "NEW" {
+		t.Errorf("added %+v, want NEW", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Name != "GONE" {
+		t.Errorf("removed %+v, want GONE", d.Removed)
+	}
+	want := []TitleChange{
+		{Key: "RECLASSIFIED", Name: "RECLASSIFIED", RatingsAdded: []Rating{pg}},
+		{Key: "RENAMED", Name: "NEW NAME", OldName: "OLD NAME", RatingsRemoved: []Rating{pg}},
+	}
+	if !reflect.DeepEqual(d.Modified, want) {
+		t.Errorf("modified %+v, want %+v", d.Modified, want)
+	}
+
+	d = DiffTitles(old, old)
+	if len(d.Added)+len(d.Removed)+len(d.Modified) != 0 {
+		t.Errorf("a set differs from itself: %+v", d)
+	}
+}
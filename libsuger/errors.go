@@ -0,0 +1,82 @@
+package libsuger
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNoSuchRow is the error (wrapped in a CrawlError) recorded when the site answers a row's request with a not-found status, or with the search page instead of a title page, meaning the row has no title page. CrawlRange skips such a row rather than retrying it.
+var ErrNoSuchRow = errors.New("no such row")
+
+// Stages of a crawl, as recorded in a CrawlError.
+const (
+	StageBackoff = "backoff" // waiting before a retry
+	StageInit    = "init"    // loading the search form
+	StageSearch  = "search"  // submitting the search
+	StagePage    = "page"    // requesting a search result page
+	StageRow     = "row"     // requesting a title page from a row
+	StageRefresh = "refresh" // starting a new session after one expired
+)
+
+// CrawlError is the error Crawl records on Job.Error. It wraps the underlying error Err with the Job's range (Start, its next result, and Stop, one past its last), the search result Page and Row the Job had reached, the Stage of the crawl that failed, and the Attempt (counted from one) that failed.
+type CrawlError struct {
+	Start   int
+	Stop    int
+	Page    int
+	Row     int
+	Stage   string
+	Attempt int
+	Err     error
+}
+
+func (e *CrawlError) Error() string {
+	return fmt.Sprintf("results %v-%v, page %v, row %v, attempt %v: %v: %v", e.Start, e.Stop-1, e.Page, e.Row, e.Attempt, e.Stage, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CrawlError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable returns false if retrying the Job would only fail the same way again: if Err is a ParseError from a row (a title page the crawl can't make sense of), or a StatusError with a 4xx status. A ParseError from any other stage (e.g. a search form missing its hidden fields) is taken to be transient, like any other error, such as a network error or timeout, a 5xx status, a ThrottleError, ErrBlockedPage or ErrSessionExpired: the site may be having a bad moment. CrawlRange gives up on a result at once if its error isn't Retryable.
+func (e *CrawlError) Retryable() bool {
+	var pe *ParseError
+	if errors.As(e.Err, &pe) && e.Stage == StageRow {
+		return false
+	}
+	var se *StatusError
+	if errors.As(e.Err, &se) && se.Status >= 400 && se.Status < 500 {
+		return false
+	}
+	return true
+}
+
+// StatusError is the error returned when the site answers a request with an unexpected HTTP status: a 5xx status to any request (other than the throttle responses of ThrottleError), or anything but a 200 OK to a row's request (other than the statuses meaning ErrNoSuchRow).
+type StatusError struct {
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %v %v", e.Status, http.StatusText(e.Status))
+}
+
+// ParseError is the error returned when a page the site sent isn't what the crawl expected, e.g. it lacks the hidden form fields that must be posted back. It wraps the underlying error Err.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ErrBlockedPage is the error returned when the site answers a row's request with a page that isn't one of its own forms at all, such as a block page or an error page from a proxy in front of it. Unlike a ParseError, it is taken to be transient (see CrawlError.Retryable).
+var ErrBlockedPage = errors.New("not a page of the site (blocked?)")
+
+// ErrSessionExpired is the error returned when the site answers a postback with a new search form, because the session (and the form's magic strings) have expired. Crawl recovers from it by starting a new session.
+var ErrSessionExpired = errors.New("session expired")
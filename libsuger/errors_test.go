@@ -0,0 +1,210 @@
+package libsuger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCheckResponse(t *testing.T) {
+	site := &fakeSite{Total: 25}
+	tests := []struct {
+		name string
+		html string
+		want error
+	}{
+		{"title page", fakeTitlePage(1), nil},
+		{"search result page", site.resultPage(1), ErrNoSuchRow},
+		{"search form", fakeSearchForm, ErrSessionExpired},
+		{"block page", "<html><body><h1>Access Denied</h1></body></html>", ErrBlockedPage},
+	}
+	for _, tt := range tests {
+		if err := checkResponse([]byte(tt.html)); err != tt.want {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// a form of the site's, but not one the crawl knows
+	err := checkResponse([]byte(`<html><body><form id="form1"><span id="lblTitle"></span></form></body></html>`))
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Errorf("empty title: got error %v, want a ParseError", err)
+	}
+}
+
+func TestCrawlNotFoundRow(t *testing.T) {
+	site, srv := newFakeSite(t, 25)
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if r.Event == "Title$2" {
+			http.NotFound(w, nil)
+			return true
+		}
+		return false
+	}
+	var results []Result
+	sum, err := CrawlRange(context.Background(), 1, 5, 1, storeResults(&results), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Results != 4 || sum.Missing != 1 || sum.Retries != 0 {
+		t.Errorf("summary %+v, want 4 results and 1 missing, with no retries", sum)
+	}
+	asked := 0
+	for _, e := range site.Events() {
+		if e == "Title$2" {
+			asked = asked + 1
+		}
+	}
+	if asked != 1 {
+		t.Errorf("missing row requested %v times, want once", asked)
+	}
+}
+
+func TestCrawlNetworkError(t *testing.T) {
+	site, srv := newFakeSite(t, 25)
+	failed := false
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if r.Event == "Title$2" && !failed {
+			// drop the connection without answering
+			failed = true
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return false
+			}
+			conn.Close()
+			return true
+		}
+		return false
+	}
+	var results []Result
+	sum, err := CrawlRange(context.Background(), 1, 5, 1, storeResults(&results), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Results != 5 || sum.Missing != 0 || sum.Retries != 1 {
+		t.Errorf("summary %+v, want 5 results after 1 retry", sum)
+	}
+	got := make(map[int]bool)
+	for _, r := range results {
+		got[r.Index] = true
+	}
+	if !got[3] {
+		t.Errorf("result 3 wasn't crawled on retry")
+	}
+}
+
+func TestCrawlErrorBurst(t *testing.T) {
+	site, srv := newFakeSite(t, 300)
+	// the site's first 200 answers are all errors, half of them dropped
+	// connections, so that the workers' Jobs come back failed all at once
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if n > 200 {
+			return false
+		}
+		if n%2 == 0 {
+			http.Error(w, "no", http.StatusInternalServerError)
+			return true
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		conn.Close()
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var results []Result
+	sum, err := CrawlRange(ctx, 1, 300, 16, storeResults(&results), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}), WithChunkSize(3))
+	if err != nil {
+		t.Fatalf("%v (summary %+v)", err, sum)
+	}
+	got := make(map[int]int)
+	for _, r := range results {
+		got[r.Index] = got[r.Index] + 1
+	}
+	for i := 1; i <= 300; i++ {
+		if got[i] != 1 {
+			t.Errorf("result %v stored %v times", i, got[i])
+		}
+	}
+	if sum.Retries < 100 {
+		t.Errorf("only %v retries; the errors didn't come in a burst", sum.Retries)
+	}
+}
+
+func TestCrawlErrorRetryable(t *testing.T) {
+	parse := &ParseError{errors.New("title is the empty string")}
+	tests := []struct {
+		stage     string
+		err       error
+		retryable bool
+	}{
+		{StageRow, parse, false},
+		{StageRow, fmt.Errorf("reading: %w", parse), false},
+		{StageInit, parse, true}, // e.g. a search form missing its hidden fields
+		{StageSearch, parse, true},
+		{StagePage, parse, true},
+		{StageRow, &StatusError{Status: http.StatusBadRequest}, false},
+		{StagePage, &StatusError{Status: http.StatusNotFound}, false},
+		{StageRow, &StatusError{Status: http.StatusInternalServerError}, true},
+		{StageRow, &StatusError{Status: http.StatusBadGateway}, true},
+		{StageRow, &ThrottleError{Status: http.StatusTooManyRequests}, true},
+		{StageRow, &ThrottleError{Status: http.StatusForbidden}, true}, // a 4xx, but a ThrottleError
+		{StageRow, ErrBlockedPage, true},
+		{StageRow, ErrSessionExpired, true},
+		{StageRefresh, ErrSessionExpired, true},
+		{StageRow, io.ErrUnexpectedEOF, true},
+		{StageRow, &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{StageRow, context.DeadlineExceeded, true},
+		{StageBackoff, context.Canceled, true},
+	}
+	for _, tt := range tests {
+		e := &CrawlError{Stage: tt.stage, Err: tt.err}
+		if got := e.Retryable(); got != tt.retryable {
+			t.Errorf("%v at %v: Retryable() = %v, want %v", tt.err, tt.stage, got, tt.retryable)
+		}
+	}
+}
+
+func TestCrawlNotRetryable(t *testing.T) {
+	site, srv := newFakeSite(t, 25)
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		switch r.Event {
+		case "Title$1":
+			// one of the site's forms, but no title: a ParseError
+			fmt.Fprint(w, `<html><body><form id="form1"><span id="lblTitle"></span></form></body></html>`)
+			return true
+		case "Title$3":
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return true
+		}
+		return false
+	}
+	var results []Result
+	sum, err := CrawlRange(context.Background(), 1, 5, 1, storeResults(&results), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}), WithMaxAttempts(5))
+	if err == nil {
+		t.Error("CrawlRange succeeded, having given up on results")
+	}
+	if sum.Results != 3 || sum.Failed != 2 || sum.Retries != 0 {
+		t.Errorf("summary %+v, want 3 results and 2 failed, with no retries", sum)
+	}
+	if len(sum.Failures) == 2 && (sum.Failures[0].Index != 2 || sum.Failures[1].Index != 4) {
+		t.Errorf("gave up on %+v, want results 2 and 4", sum.Failures)
+	}
+	asked := make(map[string]int)
+	for _, e := range site.Events() {
+		asked[e] = asked[e] + 1
+	}
+	for _, e := range []string{"Title$1", "Title$3"} {
+		if asked[e] != 1 {
+			t.Errorf("%v requested %v times, want once", e, asked[e])
+		}
+	}
+}
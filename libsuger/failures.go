@@ -0,0 +1,42 @@
+package libsuger
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/colinhb/suger/internal/atomicfile"
+	"io/ioutil"
+)
+
+// FailedResult records a result CrawlRange gave up on: its index (counting from 1, as for NewJob), its search result page and row, and the last error it failed with.
+type FailedResult struct {
+	Index int    `json:"index"`
+	Page  int    `json:"page"`
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// SaveFailures writes failures to path as JSON, for LoadFailures. The file is replaced atomically.
+func SaveFailures(path string, failures []FailedResult) error {
+	data, err := json.MarshalIndent(failures, "", "	")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, data, 0644)
+}
+
+// LoadFailures reads the failures saved at path by SaveFailures. It returns an error if there are none, since there would be nothing to retry.
+func LoadFailures(path string) ([]FailedResult, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var failures []FailedResult
+	err = json.Unmarshal(data, &failures)
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) == 0 {
+		return nil, errors.New(path + ": no failed results")
+	}
+	return failures, nil
+}
@@ -0,0 +1,95 @@
+package libsuger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNoSuchTitle is the error (wrapped) returned by Fetch when the site has no title page for the ID or URL given.
+var ErrNoSuchTitle = errors.New("no such title")
+
+// detailPath is the title page under the search page's directory, to which the ID (sRowID) and classification type (sType) are added as query parameters.
+const detailPath = "SearchDetail.aspx"
+
+// titleURL returns the URL of the title page with the given ID and classification type (e.g. "Feature"), relative to the Crawler's search page.
+func (c *Crawler) titleURL(id string, sType string) (string, error) {
+	base, err := url.Parse(c.start)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("sType", sType)
+	q.Set("sRowID", id)
+	u := base.ResolveReference(&url.URL{Path: detailPath, RawQuery: q.Encode()})
+	return u.String(), nil
+}
+
+// Fetch retrieves a single title page, given its ID (as in Title.ID) or its full URL (as in Title.URL or Result.URL), without searching or paging. An ID is looked up as a feature, then as a serial. If the site wants a session first, Fetch starts one and tries again. It returns an error wrapping ErrNoSuchTitle if the site has no such title.
+func (c *Crawler) Fetch(ctx context.Context, ref string) (Result, error) {
+	var urls []string
+	if strings.Contains(ref, "://") {
+		urls = []string{ref}
+	} else {
+		for _, t := range SearchTypes {
+			u, err := c.titleURL(ref, searchTypeValues[t])
+			if err != nil {
+				return Result{}, err
+			}
+			urls = append(urls, u)
+		}
+	}
+	for _, u := range urls {
+		result, err := c.fetchTitle(ctx, u)
+		if errors.Is(err, ErrSessionExpired) && c.magicStrings == nil {
+			c.logger.Debug("starting a session to fetch title", "url", u)
+			err = c.doInit(ctx)
+			if err != nil {
+				return Result{}, err
+			}
+			result, err = c.fetchTitle(ctx, u)
+		}
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, ErrNoSuchTitle) {
+			return Result{}, err
+		}
+	}
+	return Result{}, fmt.Errorf("%s: %w", ref, ErrNoSuchTitle)
+}
+
+// fetchTitle requests the title page at u. It returns ErrSessionExpired if the site answers with the search form, and ErrNoSuchTitle if it answers with anything else that isn't a title page.
+func (c *Crawler) fetchTitle(ctx context.Context, u string) (Result, error) {
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return Result{}, ErrNoSuchTitle
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, &StatusError{Status: resp.StatusCode}
+	}
+	html, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Result{}, err
+	}
+	err = checkResponse(html)
+	if errors.Is(err, ErrSessionExpired) {
+		return Result{}, err
+	}
+	if err != nil {
+		return Result{}, ErrNoSuchTitle
+	}
+	result := Result{
+		URL:  resp.Request.URL.String(),
+		HTML: html,
+	}
+	return result, nil
+}
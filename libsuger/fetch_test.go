@@ -0,0 +1,87 @@
+package libsuger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFetchSite returns an httptest.Server serving title pages by ID and classification type, as the site's SearchDetail.aspx does, and the requests made to it. Like the site, it gives the search form back for a title page asked for without a session.
+func newFetchSite(t *testing.T) (*httptest.Server, *[]string) {
+	titles := map[string]string{
+		"Feature/F1": titlePage("F1", "A FEATURE", Rating{"Parental Guidance", "Passed Clean", ""}),
+		"Serial/S1":  titlePage("S1", "A SERIAL", Rating{"General", "Passed Clean", ""}),
+	}
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Path {
+		case searchPath:
+			http.SetCookie(w, &http.Cookie{Name: "ASP.NET_SessionId", Value: "fetch"})
+			fmt.Fprint(w, fakeSearchForm)
+		case searchPath + detailPath:
+			if _, err := r.Cookie("ASP.NET_SessionId"); err != nil {
+				fmt.Fprint(w, fakeSearchForm)
+				return
+			}
+			page, ok := titles[r.URL.Query().Get("sType")+"/"+r.URL.Query().Get("sRowID")]
+			if !ok {
+				// the site's error page, in its usual form
+				fmt.Fprint(w, `<html><body><form method="post" action="./" id="form1"><span id="lblError">Record not found.</span></form></body></html>`)
+				return
+			}
+			fmt.Fprint(w, page)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestFetch(t *testing.T) {
+	srv, requests := newFetchSite(t)
+	c, err := NewCrawler(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ref      string
+		name     string
+		requests int
+	}{
+		// the first fetch starts a session; later ones reuse it
+		{"F1", "A FEATURE", 3},
+		{"S1", "A SERIAL", 2},
+		{srv.URL + searchPath + detailPath + "?sType=Feature&sRowID=F1", "A FEATURE", 1},
+	}
+	for _, tt := range tests {
+		*requests = nil
+		r, err := c.Fetch(context.Background(), tt.ref)
+		if err != nil {
+			t.Errorf("%v: %v", tt.ref, err)
+			continue
+		}
+		title, err := NewTitleFromHTML(r.HTML)
+		if err != nil {
+			t.Errorf("%v: %v", tt.ref, err)
+			continue
+		}
+		if title.Name != tt.name {
+			t.Errorf("%v: fetched %q, want %q", tt.ref, title.Name, tt.name)
+		}
+		if len(*requests) != tt.requests {
+			t.Errorf("%v: made requests %q, want %v", tt.ref, *requests, tt.requests)
+		}
+	}
+	for _, ref := range []string{"NOSUCHID", srv.URL + searchPath + detailPath + "?sType=Feature&sRowID=S1", srv.URL + "/Gone.aspx"} {
+		_, err := c.Fetch(context.Background(), ref)
+		if !errors.Is(err, ErrNoSuchTitle) {
+			t.Errorf("%v: got error %v, want ErrNoSuchTitle", ref, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package libsuger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ratingIndex returns the position of rating in the rating order (see RatingOrder) followed by the legacy ratings (see LegacyRatingOrder), matched regardless of case. It returns an error if the rating isn't in either.
+func ratingIndex(rating string) (int, error) {
+	all := allRatings()
+	for i, r := range all {
+		if strings.EqualFold(r, rating) {
+			return i, nil
+		}
+	}
+	msg := fmt.Sprintf("%q is not a known rating (want one of %q)", rating, all)
+	return 0, errors.New(msg)
+}
+
+// MaxRatingIs returns a filter that is true for Titles whose MaxRating is rating (matched regardless of case). Titles with no recognized rating never match. It returns an error if rating isn't in the rating order (see RatingOrder).
+func MaxRatingIs(rating string) (func(*Title) bool, error) {
+	want, err := ratingIndex(rating)
+	if err != nil {
+		return nil, err
+	}
+	return func(t *Title) bool {
+		return t.ratingRank() == want
+	}, nil
+}
+
+// MaxRatingAtLeast returns a filter that is true for Titles whose MaxRating is min or "higher" in the rating order. Titles with no recognized rating never match. It returns an error if min isn't in the rating order (see RatingOrder).
+func MaxRatingAtLeast(min string) (func(*Title) bool, error) {
+	limit, err := ratingIndex(min)
+	if err != nil {
+		return nil, err
+	}
+	return func(t *Title) bool {
+		return t.ratingRank() <= limit
+	}, nil
+}
+
+// Unrated is a filter that is true for Titles with no recognized rating (for which MaxRating returns false).
+func Unrated(t *Title) bool {
+	_, ok := t.MaxRating()
+	return !ok
+}
@@ -0,0 +1,66 @@
+package libsuger
+
+import (
+	"testing"
+)
+
+// ratedTitles returns a Title with each rating of the rating order, one with a legacy rating, and one with no recognized rating, each named by its (max) rating.
+func ratedTitles() []*Title {
+	var titles []*Title
+	for _, r := range RatingOrder() {
+		titles = append(titles, &Title{Name: r, Ratings: []Rating{{Rating: r, Decision: "Passed Clean"}, {Rating: "General Viewing", Decision: "Passed Clean"}}})
+	}
+	titles = append(titles, &Title{Name: "RA", Ratings: []Rating{{Rating: "RA", Decision: "Passed Clean"}}})
+	titles = append(titles, &Title{Name: "UNRATED", Ratings: []Rating{{Rating: "Pending", Decision: ""}}})
+	return titles
+}
+
+// kept returns the Names of the titles f is true for.
+func kept(titles []*Title, f func(*Title) bool) []string {
+	var names []string
+	for _, t := range titles {
+		if f(t) {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+func TestMaxRatingFilters(t *testing.T) {
+	titles := ratedTitles()
+	tests := []struct {
+		name   string
+		filter func(string) (func(*Title) bool, error)
+		rating string
+		want   []string
+	}{
+		{"MaxRatingIs", MaxRatingIs, "Matured Above 18", []string{"Matured Above 18"}},
+		{"MaxRatingIs", MaxRatingIs, "matured above 18", []string{"Matured Above 18"}},
+		{"MaxRatingIs", MaxRatingIs, "General Viewing", []string{"General Viewing"}},
+		{"MaxRatingIs", MaxRatingIs, "RA", []string{"RA"}},
+		{"MaxRatingAtLeast", MaxRatingAtLeast, "No Children Under 16", []string{"Restricted 21", "Matured Above 18", "No Children Under 16"}},
+		{"MaxRatingAtLeast", MaxRatingAtLeast, "Restricted 21", []string{"Restricted 21"}},
+		// the legacy ratings rank below the rating order
+		{"MaxRatingAtLeast", MaxRatingAtLeast, "RA", append(RatingOrder(), "RA")},
+	}
+	for _, tt := range tests {
+		f, err := tt.filter(tt.rating)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := kept(titles, f); !equalStrings(got, tt.want) {
+			t.Errorf("%v(%q) kept %q, want %q", tt.name, tt.rating, got, tt.want)
+		}
+	}
+	if got := kept(titles, Unrated); !equalStrings(got, []string{"UNRATED"}) {
+		t.Errorf("Unrated kept %q, want only UNRATED", got)
+	}
+	for _, rating := range []string{"", "R21", "Pending"} {
+		if _, err := MaxRatingIs(rating); err == nil {
+			t.Errorf("MaxRatingIs(%q) succeeded", rating)
+		}
+		if _, err := MaxRatingAtLeast(rating); err == nil {
+			t.Errorf("MaxRatingAtLeast(%q) succeeded", rating)
+		}
+	}
+}
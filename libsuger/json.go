@@ -0,0 +1,115 @@
+package libsuger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReadTitles decodes a JSON array of Titles (the format written by suger scrape) from r one element at a time, calling fn for each. The array may also be the "titles" of an envelope object (see suger scrape -envelope), whose other fields are ignored. Memory use stays flat regardless of the size of the input. It stops and returns the first error returned by fn.
+func ReadTitles(r io.Reader, fn func(*Title) error) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); ok && d == '{' {
+		return readEnvelope(dec, fn)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		msg := fmt.Sprintf("expected start of JSON array, got %v", tok)
+		return errors.New(msg)
+	}
+	return readTitleArray(dec, fn)
+}
+
+// readEnvelope finds the "titles" field of the envelope object whose opening brace dec has just read, skipping any others, and reads its array with readTitleArray. The rest of the object is not read.
+func readEnvelope(dec *json.Decoder, fn func(*Title) error) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key, ok := tok.(string); ok && key == "titles" {
+			tok, err = dec.Token()
+			if err != nil {
+				return err
+			}
+			if d, ok := tok.(json.Delim); !ok || d != '[' {
+				msg := fmt.Sprintf("expected start of JSON array of titles, got %v", tok)
+				return errors.New(msg)
+			}
+			return readTitleArray(dec, fn)
+		}
+		var skip json.RawMessage
+		err = dec.Decode(&skip)
+		if err != nil {
+			return err
+		}
+	}
+	return errors.New("no titles in JSON object")
+}
+
+// readTitleArray decodes the Titles of the array whose opening bracket dec has just read, calling fn for each, and consumes the closing bracket.
+func readTitleArray(dec *json.Decoder, fn func(*Title) error) error {
+	for dec.More() {
+		t := &Title{}
+		err := dec.Decode(t)
+		if err != nil {
+			return err
+		}
+		err = fn(t)
+		if err != nil {
+			return err
+		}
+	}
+	// consume the closing bracket
+	_, err := dec.Token()
+	return err
+}
+
+// jobJSON is the JSON form of a Job. Its Error is kept as its message only.
+type jobJSON struct {
+	Start       int    `json:"start"`
+	Stop        int    `json:"stop"`
+	Attempts    int    `json:"attempts,omitempty"`
+	MaxAttempts int    `json:"max_attempts,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the Job as an object with its range (start, and stop one past its last result), its attempts and max_attempts, and the message of its last error, if any.
+func (j Job) MarshalJSON() ([]byte, error) {
+	jj := jobJSON{
+		Start:       j.start,
+		Stop:        j.stop,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+	}
+	if j.Error != nil {
+		jj.Error = j.Error.Error()
+	}
+	return json.Marshal(jj)
+}
+
+// UnmarshalJSON decodes a Job encoded by MarshalJSON. The Job's Error, if any, is restored as a plain error with the same message. It returns an error if the range is invalid.
+func (j *Job) UnmarshalJSON(data []byte) error {
+	var jj jobJSON
+	err := json.Unmarshal(data, &jj)
+	if err != nil {
+		return err
+	}
+	if jj.Start < 1 || jj.Stop < jj.Start {
+		msg := fmt.Sprintf("invalid Job range: start %v, stop %v", jj.Start, jj.Stop)
+		return errors.New(msg)
+	}
+	j.start = jj.Start
+	j.stop = jj.Stop
+	j.Attempts = jj.Attempts
+	j.MaxAttempts = jj.MaxAttempts
+	j.Error = nil
+	if jj.Error != "" {
+		j.Error = errors.New(jj.Error)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package libsuger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// writeSyntheticTitles writes a JSON array of n Titles to w, one at a time, and closes it.
+func writeSyntheticTitles(w *io.PipeWriter, n int) {
+	enc := json.NewEncoder(w)
+	io.WriteString(w, "[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			io.WriteString(w, ",")
+		}
+		t := &Title{
+			ID:      fakeID(i),
+			Name:    fakeName(i),
+			Ratings: []Rating{{Rating: "Parental Guidance", Decision: "Passed Clean"}},
+		}
+		err := enc.Encode(t)
+		if err != nil {
+			w.CloseWithError(err)
+			return
+		}
+	}
+	io.WriteString(w, "]")
+	w.Close()
+}
+
+func TestReadTitlesLargeArray(t *testing.T) {
+	const n = 100000
+	r, w := io.Pipe()
+	go writeSyntheticTitles(w, n)
+	count := 0
+	err := ReadTitles(r, func(title *Title) error {
+		if title.Name != fakeName(count) || len(title.Ratings) != 1 {
+			return fmt.Errorf("title %v is %+v", count, title)
+		}
+		count = count + 1
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Errorf("read %v titles, want %v", count, n)
+	}
+}
+
+func TestReadTitlesStopsOnError(t *testing.T) {
+	r, w := io.Pipe()
+	go writeSyntheticTitles(w, 10)
+	defer r.Close()
+	stop := errors.New("stop")
+	count := 0
+	err := ReadTitles(r, func(*Title) error {
+		count = count + 1
+		if count == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("got error %v, want %v", err, stop)
+	}
+	if count != 3 {
+		t.Errorf("fn called %v times, want 3", count)
+	}
+}
+
+func TestReadTitlesNotArray(t *testing.T) {
+	for _, in := range []string{`"title"`, `42`, ``, `[{"Name": "A"}`} {
+		err := ReadTitles(strings.NewReader(in), func(*Title) error { return nil })
+		if err == nil {
+			t.Errorf("ReadTitles(%q) succeeded", in)
+		}
+	}
+}
+
+func TestJobJSONRoundTrip(t *testing.T) {
+	j, _ := NewJob(3, 100)
+	parts, err := j.Partition(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts[2].Attempts = 2
+	parts[2].MaxAttempts = 5
+	parts[2].Error = errors.New("connection reset")
+	data, err := json.Marshal(parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Job
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(parts) {
+		t.Fatalf("got %v Jobs back, want %v", len(got), len(parts))
+	}
+	for i := range parts {
+		a, b := parts[i], got[i]
+		if a.Start() != b.Start() || a.Stop() != b.Stop() || a.Count() != b.Count() || a.Attempts != b.Attempts || a.MaxAttempts != b.MaxAttempts {
+			t.Errorf("Job %v: %v came back as %v", i, a, b)
+		}
+		if (a.Error == nil) != (b.Error == nil) || (a.Error != nil && a.Error.Error() != b.Error.Error()) {
+			t.Errorf("Job %v: error %v came back as %v", i, a.Error, b.Error)
+		}
+	}
+	checkPartitions(t, j, got)
+}
+
+func TestJobUnmarshalInvalid(t *testing.T) {
+	for _, in := range []string{`{"start": 0, "stop": 5}`, `{"start": 5, "stop": 4}`, `{"start": "one"}`} {
+		var j Job
+		err := json.Unmarshal([]byte(in), &j)
+		if err == nil {
+			t.Errorf("Unmarshal(%s) succeeded: %v", in, j)
+		}
+	}
+}
@@ -3,25 +3,38 @@ package libsuger
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
+	"io"
 	"io/ioutil"
-	// "log"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
 )
 
-// Job is a type that stores certain state information used by the Crawl method the Crawler type. Its only exported field is Error, which contains the last error recorded by Crawl method.
+// Job is a type that stores certain state information used by the Crawl method the Crawler type. Its exported fields are Error, which contains the last error recorded by Crawl method (a *CrawlError), Attempts, the number of consecutive times Crawl has failed on it (reset whenever a result is crawled successfully), and MaxAttempts, the number of attempts after which the Job counts as Failed (zero means no limit).
 type Job struct {
-	start int
-	stop  int
-	Error error
+	start       int
+	stop        int
+	Error       error
+	Attempts    int
+	MaxAttempts int
+	crawler     *Crawler // the Crawler that failed on a row of the Job, whose session can be reused (see Crawl)
 }
 
-// NewJob creates a Job from the first result you want to crawl (start) and the number of results (count) that you want to crawl. It returns an error if start or count are less than one.
+// MaxResults is the highest result number a Job may reach: far more results than the database has ever held, but low enough that no range within it overflows an int, even on 32-bit platforms.
+const MaxResults = 1 << 30
+
+// NewJob creates a Job from the first result you want to crawl (start) and the number of results (count) that you want to crawl. It returns an error if start or count are less than one, or if the Job would go past result MaxResults.
 func NewJob(start int, count int) (Job, error) {
 	j := Job{}
 	if !(start > 0 && count > 0) {
@@ -29,6 +42,12 @@ func NewJob(start int, count int) (Job, error) {
 		err := errors.New(fmt.Sprintf(s, start, count))
 		return j, err
 	}
+	// compare without adding, which could overflow
+	if start > MaxResults || count > MaxResults-start+1 {
+		s := "start (%v) and count (%v) go past the last possible result (%v)."
+		err := errors.New(fmt.Sprintf(s, start, count, MaxResults))
+		return j, err
+	}
 	j.start = start
 	j.stop = start + count
 	return j, nil
@@ -39,55 +58,252 @@ func (j Job) next() Job {
 	return j
 }
 
+// Failed returns true if the Job has used up its MaxAttempts without crawling its next result.
+func (j Job) Failed() bool {
+	return j.MaxAttempts > 0 && j.Attempts >= j.MaxAttempts
+}
+
+// Skip returns the Job moved past the result it is failing on, with its Error and Attempts cleared, so the rest of the Job can still be crawled.
+func (j Job) Skip() Job {
+	j = j.next()
+	j.Error = nil
+	j.Attempts = 0
+	return j
+}
+
 // IsDone returns true if there are no more results to crawl (i.e., all have been successfully crawled.)
 func (j Job) IsDone() bool {
 	return j.start >= j.stop
 }
 
-// Partition returns a slice of non-overlapping Jobs of roughly equal count.
+// Start returns the index of the Job's next result to crawl (counting from 1, as for NewJob).
+func (j Job) Start() int {
+	return j.start
+}
+
+// Stop returns the index one past the Job's last result.
+func (j Job) Stop() int {
+	return j.stop
+}
+
+// Count returns the number of results the Job has left to crawl.
+func (j Job) Count() int {
+	if j.IsDone() {
+		return 0
+	}
+	return j.stop - j.start
+}
+
+// String renders the Job's range of results, and its attempts and last error if it has failed.
+func (j Job) String() string {
+	if j.IsDone() {
+		return fmt.Sprintf("Job(done at %v)", j.stop-1)
+	}
+	s := fmt.Sprintf("Job(results %v-%v, %v left)", j.start, j.stop-1, j.Count())
+	if j.Attempts > 0 {
+		s = s + fmt.Sprintf(" after %v failed attempts: %v", j.Attempts, j.Error)
+	}
+	return s
+}
+
+// Partition returns a slice of non-overlapping Jobs of roughly equal count that together cover exactly the Job's results. Where the results don't divide evenly, the first partitions get one extra result each. If the Job has fewer than n results, there is one partition per result. It returns an error if n is less than one.
 func (j Job) Partition(n int) ([]Job, error) {
 	var sl []Job
-	count := j.stop - j.start
-	if count < n {
-		s := "the number of partitions (%v) must be greater than count (%v)."
-		err := errors.New(fmt.Sprintf(s, n, count))
+	count := j.Count()
+	if n < 1 {
+		s := "the number of partitions (%v) must be greater than zero."
+		err := errors.New(fmt.Sprintf(s, n))
 		return sl, err
 	}
-	q := count / n
-	if q == 0 {
-		q = 1
+	if count < n {
+		n = count
+	}
+	if n == 0 { // nothing left to crawl
+		return sl, nil
 	}
+	q := count / n
+	r := count % n
+	start := j.start
 	for i := 0; i < n; i++ {
-		start := j.start + (q * i)
-		part, _ := NewJob(start, q)
+		size := q
+		if i < r {
+			size = size + 1
+		}
+		part, _ := NewJob(start, size)
+		sl = append(sl, part)
+		start = start + size
+	}
+	return sl, nil
+}
+
+// PartitionBySize splits the Job into consecutive Jobs of size results each, the last of them holding whatever is left over. It returns an error if size is less than one, and no Jobs if the Job is done.
+func (j Job) PartitionBySize(size int) ([]Job, error) {
+	var sl []Job
+	if size < 1 {
+		s := "the partition size (%v) must be greater than zero."
+		err := errors.New(fmt.Sprintf(s, size))
+		return sl, err
+	}
+	for start := j.start; start < j.stop; start = start + size {
+		n := size
+		if start+n > j.stop {
+			n = j.stop - start
+		}
+		part, _ := NewJob(start, n)
 		sl = append(sl, part)
 	}
-	last := sl[n-1]
-	last.stop = j.stop
 	return sl, nil
 }
 
-func (j Job) page() int {
-	p := ((j.start - 1) / 20) + 1
-	return int(p)
+// ResultsPerPage is the number of results the classification database shows on each page of search results. It is the default for a Crawler (see WithResultsPerPage).
+const ResultsPerPage = 20
+
+// ResultIndex returns the index (counting from 1, as for NewJob) of the result at the given search result page (from 1) and row (from 0), given perPage results per page.
+func ResultIndex(page int, row int, perPage int) int {
+	return (page-1)*perPage + row + 1
+}
+
+// ResultPosition is the inverse of ResultIndex: it returns the search result page (from 1) and row (from 0) of the result with the given index, given perPage results per page.
+func ResultPosition(index int, perPage int) (int, int) {
+	return (index-1)/perPage + 1, (index - 1) % perPage
+}
+
+// page returns the search result page (from 1) of the Job's next result, given perPage results per page.
+func (j Job) page(perPage int) int {
+	p, _ := ResultPosition(j.start, perPage)
+	return p
+}
+
+// row returns the row (from 0) of the Job's next result on its search result page, given perPage results per page.
+func (j Job) row(perPage int) int {
+	_, r := ResultPosition(j.start, perPage)
+	return r
 }
 
-func (j Job) row() int {
-	r := ((j.start - 1) % 20)
-	return int(r)
+// Pages returns the first and last search result pages the Job's results are on, given perPage results per page.
+func (j Job) Pages(perPage int) (int, int) {
+	last, _ := ResultPosition(j.stop-1, perPage)
+	return j.page(perPage), last
+}
+
+// SeekPages returns the search result pages Crawl requests, in order, to reach the Job's first page after a search (see pagePostbacks), given perPage results per page.
+func (j Job) SeekPages(perPage int) []int {
+	return pagePostbacks(j.page(perPage))
 }
 
 // Rating is a simple type to hold a single rating (e.g. "No Children Under 16") and decision (e.g. "Passed Clean").
 type Rating struct {
-	Rating   string
-	Decision string
+	Rating    string
+	Decision  string
+	RawRating string `json:",omitempty"` // the rating exactly as the page gave it (e.g. an image's alt text), before NormalizeRating
 }
 
-// Title is a simple type to hold the Name, URL, and various Ratings for a title in the database.
+// key returns the Rating as compared with others (e.g. by DiffTitles): its normalized rating and its decision, without its RawRating.
+func (r Rating) key() Rating {
+	return Rating{Rating: NormalizeRating(r.Rating), Decision: r.Decision}
+}
+
+// Title is a simple type to hold the Name, URL, and various Ratings for a title in the database. Warnings lists anything unexpected NewTitleFromHTML noticed about the page that wasn't serious enough to be an error (e.g. that it had no ratings), which may mean the page's layout has changed.
 type Title struct {
-	Name    string
-	Ratings []Rating
-	URL     string
+	ID          string // the record's sRowID, which identifies it in the database
+	Type        string // the record's classification type: one of SearchTypes (e.g. "feature"), or for a type suger doesn't search for, the site's name for it in lower case; empty if the page doesn't say
+	Name        string
+	Ratings     []Rating
+	URL         string
+	Distributor string   // the first distributor named in the title's ratings table, if any
+	RunningTime int      // minutes, from the first rating with a duration (for serials, the total for the episodes rated); zero if unknown
+	Advice      []string `json:",omitempty"` // the consumer advice given with the title's ratings (e.g. "Some Violence"), each distinct piece once, in order; empty if none was given
+	Warnings    []string `json:",omitempty"`
+}
+
+// placeholders are the cell values the site uses for "not applicable".
+var placeholders = map[string]bool{
+	"":    true,
+	"-":   true,
+	"NA":  true,
+	"N/A": true,
+	"N.A": true,
+}
+
+// cellValue returns the trimmed text of a table cell, or the empty string if the cell holds a placeholder such as "N/A".
+func cellValue(s *goquery.Selection) string {
+	v := strings.TrimSpace(s.Text())
+	if placeholders[v] {
+		return ""
+	}
+	return v
+}
+
+// adviceValue returns the text of a consumer advice cell, with the lines it is split into (often English, then Chinese) joined by spaces, or the empty string if the cell holds a placeholder such as "-".
+func adviceValue(s *goquery.Selection) string {
+	var lines []string
+	s.Contents().Each(func(i int, n *goquery.Selection) {
+		line := strings.Join(strings.Fields(n.Text()), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	})
+	v := strings.Join(lines, " ")
+	if placeholders[v] {
+		return ""
+	}
+	return v
+}
+
+// parseAdvice returns the consumer advice from each rating's "Consumer Advice" table, dropping repeats (ignoring case) and placeholders.
+func parseAdvice(doc *goquery.Document) []string {
+	var advice []string
+	seen := make(map[string]bool)
+	doc.Find("div#content td b").Each(func(i int, s *goquery.Selection) {
+		if strings.TrimSpace(s.Text()) != "Consumer Advice" {
+			return
+		}
+		v := adviceValue(s.Closest("td").Next().Find("div"))
+		key := strings.ToLower(v)
+		if v == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		advice = append(advice, v)
+	})
+	return advice
+}
+
+// parseID returns the record ID (the sRowID query parameter) from a title page URL such as "SearchDetail.aspx?sType=Feature&sRowID=AAAH4UAAPAAABBpAAI", or the empty string if it has none.
+func parseID(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("sRowID")
+}
+
+// parseType returns the classification type of a title page from its URL's sType (e.g. "Feature"), as one of SearchTypes if it is one of them (see searchTypeValues), or else lower-cased. It returns the empty string if the URL has no sType.
+func parseType(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	sType := strings.TrimSpace(u.Query().Get("sType"))
+	for t, v := range searchTypeValues {
+		if strings.EqualFold(v, sType) {
+			return t
+		}
+	}
+	return strings.ToLower(sType)
+}
+
+// minutesRe matches the number of minutes at the start of a duration cell, e.g. "120" or "120 mins".
+var minutesRe = regexp.MustCompile(`^([0-9]+)`)
+
+// parseMinutes returns the number of minutes in a duration cell, or zero if it doesn't hold one.
+func parseMinutes(s string) int {
+	m := minutesRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
 }
 
 func NewTitleFromHTML(html []byte) (*Title, error) {
@@ -98,18 +314,43 @@ func NewTitleFromHTML(html []byte) (*Title, error) {
 	}
 	name := strings.TrimSpace(doc.Find("#lblTitle").Text())
 	var ratings []Rating
-	ratImg := doc.Find("div#content td img")
+	var distributor string
+	var runningTime int
+	// each rating is a row ending in rating, decision, duration, and
+	// distributor cells, after format and region (and for serials,
+	// title and episode); the header row's cells are bold
+	rows := doc.Find("div#content tr")
 	// use something other than Each....
-	ratImg.Each(func(i int, s *goquery.Selection) {
-		rat, ok := s.Attr("alt")
-		if !ok {
-			err = errors.New("No 'alt' attribute.")
+	rows.Each(func(i int, s *goquery.Selection) {
+		tds := s.ChildrenFiltered("td")
+		if tds.Length() < 6 || s.Find("b").Length() > 0 || err != nil {
 			return
 		}
-		dec := s.Closest("td").Next().Text()
+		td := tds.Eq(tds.Length() - 4)
+		dec := td.Next().Text()
+		var rat string
+		if img := td.Find("img"); img.Length() > 0 {
+			var ok bool
+			rat, ok = img.Attr("alt")
+			if !ok {
+				err = errors.New("No 'alt' attribute.")
+				return
+			}
+		} else {
+			// older records give the rating as text (e.g. "RA"), and
+			// refusals none at all
+			rat = legacyRating(cellValue(td), dec)
+		}
+		if runningTime == 0 {
+			runningTime = parseMinutes(cellValue(td.NextAll().Eq(1)))
+		}
+		if distributor == "" {
+			distributor = cellValue(td.NextAll().Eq(2))
+		}
 		rating := Rating{
-			Rating:   rat,
-			Decision: dec,
+			Rating:    NormalizeRating(rat),
+			Decision:  dec,
+			RawRating: rat,
 		}
 		ratings = append(ratings, rating)
 	})
@@ -121,16 +362,54 @@ func NewTitleFromHTML(html []byte) (*Title, error) {
 		err = errors.New("No 'action' attribute.")
 		return nil, err
 	}
+	id := parseID(u)
+	sType := parseType(u)
 	u = fmt.Sprintf("https://app.mda.gov.sg/Classification/Search/Film/%v", u)
 	title := &Title{
-		Name:    name,
-		Ratings: ratings,
-		URL:     u,
+		ID:          id,
+		Type:        sType,
+		Name:        name,
+		Ratings:     ratings,
+		URL:         u,
+		Distributor: distributor,
+		RunningTime: runningTime,
+		Advice:      parseAdvice(doc),
+	}
+	if len(ratings) == 0 {
+		title.Warnings = append(title.Warnings, "no ratings found")
+	}
+	if id == "" {
+		title.Warnings = append(title.Warnings, "no ID found in URL")
 	}
 	return title, nil
 }
 
-var orderedRatings []string = []string{
+// Duration returns the title's RunningTime as a time.Duration.
+func (t *Title) Duration() time.Duration {
+	return time.Duration(t.RunningTime) * time.Minute
+}
+
+// String returns a one-line summary of the title, e.g. `Title("Name", Restricted 21, 3 ratings, https://...)`, or with "no rating" in place of the maximum rating if it has none.
+func (t *Title) String() string {
+	max, ok := t.MaxRating()
+	if !ok {
+		max = "no rating"
+	}
+	return fmt.Sprintf("Title(%q, %v, %v ratings, %v)", t.Name, max, len(t.Ratings), t.URL)
+}
+
+// Refused returns true if any of the title's Ratings was refused (see Rating.Refused).
+func (t *Title) Refused() bool {
+	for i := 0; i < len(t.Ratings); i++ {
+		if t.Ratings[i].Refused() {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRatingOrder lists the ratings recognized by MaxRating and MinRating, from "highest" (most restrictive) to "lowest": "Restricted 21", "Matured Above 18", "No Children Under 16", "Parental Guidance 13", "Parental Guidance", "General Viewing".
+var DefaultRatingOrder = []string{
 	"Restricted 21",
 	"Matured Above 18",
 	"No Children Under 16",
@@ -139,57 +418,314 @@ var orderedRatings []string = []string{
 	"General Viewing",
 }
 
-// MaxRating returns the "highest" rating a title has been given. It's bool return value is false if the Title has no ratings (an ok pattern).
-func (t *Title) MaxRating() (string, bool) {
+var orderedRatings []string = DefaultRatingOrder
+
+// DefaultLegacyRatingOrder lists the ratings of older records (mostly from before the current ratings were introduced in 2004) recognized by MaxRating and MinRating, from "highest" to "lowest": "NAR" (given by NewTitleFromHTML to a record refused as "Not for All Ratings", which the site shows without a rating), "RA" (Restricted (Artistic)), and "PG/RA". They rank below every rating in the rating order (see RatingOrder), so only count for a title that has none of those.
+var DefaultLegacyRatingOrder = []string{
+	"NAR",
+	"RA",
+	"PG/RA",
+}
+
+var legacyRatings []string = DefaultLegacyRatingOrder
+
+// SetLegacyRatingOrder replaces the legacy ratings recognized by MaxRating and MinRating (see DefaultLegacyRatingOrder), and their order, from "highest" to "lowest". Like SetRatingOrder, it is not safe to call while other goroutines are using Titles. SetLegacyRatingOrder(nil) stops legacy ratings being recognized at all.
+func SetLegacyRatingOrder(order []string) {
+	legacyRatings = append([]string(nil), order...)
+}
+
+// LegacyRatingOrder returns a copy of the legacy ratings currently recognized by MaxRating and MinRating, from "highest" to "lowest".
+func LegacyRatingOrder() []string {
+	return append([]string(nil), legacyRatings...)
+}
+
+// allRatings returns every rating recognized by MaxRating and MinRating, from "highest" to "lowest": the rating order, then the legacy ratings.
+func allRatings() []string {
+	all := make([]string, 0, len(orderedRatings)+len(legacyRatings))
+	all = append(all, orderedRatings...)
+	return append(all, legacyRatings...)
+}
+
+// NormalizeRating returns rating with surrounding whitespace trimmed and runs of whitespace inside it collapsed to a single space, and if it then matches one of the ratings recognized by MaxRating and MinRating regardless of case, with that rating's casing. Small changes to the site's alt text (e.g. "restricted  21 ") then don't stop a rating being recognized.
+func NormalizeRating(rating string) string {
+	rating = strings.Join(strings.Fields(rating), " ")
+	for _, r := range allRatings() {
+		if strings.EqualFold(r, rating) {
+			return r
+		}
+	}
+	return rating
+}
+
+// legacyRating returns the rating of a row of an older record, given the text of its rating cell (empty if it held a placeholder) and its decision: the text, or "NAR" if it is empty and the decision is "Not for All Ratings" or "NAR".
+func legacyRating(text string, decision string) string {
+	if text != "" {
+		return text
+	}
+	if isNAR(normalizeDecision(decision)) {
+		return "NAR"
+	}
+	return ""
+}
+
+// SetRatingOrder replaces the ratings recognized by MaxRating and MinRating, and their order, from "highest" to "lowest". Use it to add a newly introduced rating or change the ordering without editing the package. It is not safe to call while other goroutines are using Titles. SetRatingOrder(DefaultRatingOrder) restores the default.
+func SetRatingOrder(order []string) {
+	orderedRatings = append([]string(nil), order...)
+}
+
+// RatingOrder returns a copy of the ratings currently recognized by MaxRating and MinRating, from "highest" to "lowest".
+func RatingOrder() []string {
+	return append([]string(nil), orderedRatings...)
+}
+
+// noRating is returned by MaxRating and MinRating for a Title with no recognized ratings.
+const noRating = "Missing or unrecognized rating. Check URL."
+
+// uniqueRatings returns the set of distinct ratings the title has been given, normalized (see NormalizeRating) in case the Title was scraped before they were. A Rating with no rating (as for an older record banned outright) doesn't count.
+func (t *Title) uniqueRatings() map[string]struct{} {
 	unique := make(map[string]struct{})
 	for i := 0; i < len(t.Ratings); i++ {
-		s := t.Ratings[i].Rating
+		s := NormalizeRating(t.Ratings[i].Rating)
+		if s == "" {
+			continue
+		}
 		unique[s] = struct{}{}
 	}
-	for i := 0; i < len(orderedRatings); i++ {
-		rating := orderedRatings[i]
+	return unique
+}
+
+// HasRating returns true if any of the title's Ratings is r, once both are normalized (see NormalizeRating).
+func (t *Title) HasRating(r string) bool {
+	_, ok := t.uniqueRatings()[NormalizeRating(r)]
+	return ok
+}
+
+// RatingCount returns the number of distinct ratings the title has been given. A rating given more than once (e.g. for different formats) is counted once.
+func (t *Title) RatingCount() int {
+	return len(t.uniqueRatings())
+}
+
+// MaxRating returns the "highest" rating a title has been given, of those in the rating order, or failing those the legacy ratings (see LegacyRatingOrder). It's bool return value is false if the Title has no recognized ratings (an ok pattern).
+func (t *Title) MaxRating() (string, bool) {
+	unique := t.uniqueRatings()
+	all := allRatings()
+	for i := 0; i < len(all); i++ {
+		rating := all[i]
 		if _, ok := unique[rating]; ok {
 			return rating, true
 		}
 	}
-	return "Missing, NAR, or pre-2004 rating. Check URL.", false
+	return noRating, false
+}
+
+// MaxRatingDetail is like MaxRating, but returns the whole Rating, with its Decision. If the title was given its highest rating more than once (e.g. for different formats), the Rating with the strictest decision is returned: refused, then cuts, edited, clean, and exempted. Its bool return value is false if the Title has no recognized ratings, in which case the Rating is zero.
+func (t *Title) MaxRatingDetail() (Rating, bool) {
+	max, ok := t.MaxRating()
+	if !ok {
+		return Rating{}, false
+	}
+	var best Rating
+	found := false
+	for _, r := range t.Ratings {
+		if NormalizeRating(r.Rating) != max {
+			continue
+		}
+		if !found || strictness[ParseDecision(r.Decision)] > strictness[ParseDecision(best.Decision)] {
+			best = r
+			found = true
+		}
+	}
+	return best, true
+}
+
+// MinRating returns the "lowest" rating a title has been given, of those in the rating order, or failing those the legacy ratings (see LegacyRatingOrder). It's bool return value is false if the Title has no recognized ratings, as for MaxRating.
+func (t *Title) MinRating() (string, bool) {
+	unique := t.uniqueRatings()
+	for _, order := range [][]string{orderedRatings, legacyRatings} {
+		for i := len(order) - 1; i >= 0; i-- {
+			rating := order[i]
+			if _, ok := unique[rating]; ok {
+				return rating, true
+			}
+		}
+	}
+	return noRating, false
 }
 
+// magicFields are the hidden ASP.NET form fields that must be posted back with every request.
+var magicFields = []string{
+	"__VIEWSTATE",
+	"__VIEWSTATEGENERATOR",
+	"__EVENTVALIDATION",
+}
+
+// getMagicStrings returns the values of the magicFields in html. It returns an error naming the first field that the page doesn't contain.
 func getMagicStrings(html []byte) (url.Values, error) {
 	reader := bytes.NewReader(html)
-	doc, _ := goquery.NewDocumentFromReader(reader)
-	vs, _ := doc.Find("#__VIEWSTATE").Attr("value")
-	vsg, _ := doc.Find("#__VIEWSTATEGENERATOR").Attr("value")
-	ev, _ := doc.Find("#__EVENTVALIDATION").Attr("value")
-	ms := map[string][]string{
-		"__VIEWSTATE":          []string{vs},
-		"__VIEWSTATEGENERATOR": []string{vsg},
-		"__EVENTVALIDATION":    []string{ev},
+	doc, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	ms := make(url.Values)
+	for _, field := range magicFields {
+		v, ok := doc.Find("#" + field).Attr("value")
+		if !ok {
+			msg := fmt.Sprintf("the page didn't contain a %s", field)
+			return nil, &ParseError{errors.New(msg)}
+		}
+		ms[field] = []string{v}
 	}
 	return ms, nil
 }
 
-// Crawler is a type that embeds an http.Client and holds state information. 
+// Crawler is a type that embeds an http.Client and holds state information.
 type Crawler struct {
 	http.Client
 	magicStrings url.Values
-	url          string
+	start        string // URL of the search form, where each session starts (see WithStartURL)
+	url          string // URL the form is posted back to
+	grid         []byte // the current search result page
+	page         int    // the number of the current search result page, or zero before a search
+	backoff      BackoffConfig
+	perPage      int
+	delay        time.Duration
+	header       http.Header // added to every request
+	skip         func(page int, row int) bool
+	last         time.Time // when the last request was sent
+	total        int       // total search results, if known
+	maxAttempts  int       // see WithMaxAttempts
+	chunkSize    int       // see WithChunkSize
+	gate         *gate     // shared with the other Crawlers of a CrawlRange
+	logger       *slog.Logger
+	searchTypes  []string        // see WithSearchTypes
+	searchTerm   string          // see WithSearchTerm
+	searchField  string          // name of the search form's title field
+	requests     *int64          // counts requests made (atomically); shared by the Crawlers of a CrawlRange
+	limiter      *limiter        // see WithRate
+	cookieFile   string          // see WithCookieFile
+	saved        *savedSession   // the session loaded by WithCookieFile, until NewCrawler restores it
+	savedURL     string          // the URL the saved session posts back to
+	metrics      Metrics         // see WithMetrics
+	progress     *progressConfig // see WithProgress
+	doer         HTTPDoer        // see WithHTTPDoer; nil means the embedded Client
+}
+
+// DefaultBaseURL is the root of the classification database site, and searchPath the location of the film search page beneath it.
+const (
+	DefaultBaseURL = "https://app.mda.gov.sg"
+	searchPath     = "/Classification/Search/Film/"
+)
+
+// requestLogInterval is how many requests the Crawler makes between logging the count so far (at the Debug level).
+const requestLogInterval = 100
+
+// Requests returns the number of HTTP requests the Crawler has made (from initialization, search, paging, and row requests alike), including those that failed. The Crawlers of a CrawlRange share one count.
+func (c *Crawler) Requests() int {
+	return int(atomic.LoadInt64(c.requests))
 }
 
-// NewCrawler returns a pointer to a new Crawler. 
-func NewCrawler() (*Crawler, error) {
+// DefaultTimeout is the time limit a new Crawler sets on each request (see WithTimeout).
+const DefaultTimeout = 60 * time.Second
+
+// NewCrawler returns a pointer to a new Crawler, configured by any CrawlerOptions given.
+func NewCrawler(opts ...CrawlerOption) (*Crawler, error) {
 	jar, _ := cookiejar.New(nil)
-	cl := http.Client{Jar: jar}
+	cl := http.Client{Jar: jar, Timeout: DefaultTimeout}
 	c := &Crawler{
 		Client:       cl,
 		magicStrings: nil,
-		url:          "https://app.mda.gov.sg/Classification/Search/Film/",
+		start:        DefaultBaseURL + searchPath,
+		url:          DefaultBaseURL + searchPath,
+		backoff:      DefaultBackoff,
+		perPage:      ResultsPerPage,
+		header:       make(http.Header),
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		searchTypes:  SearchTypes,
+		requests:     new(int64),
+		metrics:      noMetrics{},
 	}
+	for _, opt := range opts {
+		err := opt(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// only now are the search and site it must match known
+	c.restoreSession()
 	return c, nil
 }
 
-func (c *Crawler) doInit() error {
-	r, err := c.Get(c.url)
+// get is like http.Client.Get, but the request is aborted if ctx is done.
+func (c *Crawler) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+// postForm is like http.Client.PostForm, but the request is aborted if ctx is done.
+func (c *Crawler) postForm(ctx context.Context, u string, vals url.Values) (*http.Response, error) {
+	body := strings.NewReader(vals.Encode())
+	req, err := http.NewRequestWithContext(ctx, "POST", u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req)
+}
+
+// do sends req with the Crawler's headers, first waiting out whatever is left of the Crawler's delay since its last request, any pause of the crawl it belongs to, and its turn under its rate limit. It returns a ThrottleError if the site refuses the request.
+func (c *Crawler) do(req *http.Request) (*http.Response, error) {
+	for k, vs := range c.header {
+		req.Header[k] = vs
+	}
+	if c.delay > 0 && !c.last.IsZero() {
+		err := sleep(req.Context(), c.delay-time.Since(c.last))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.gate != nil {
+		err := c.gate.wait(req.Context())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.limiter != nil {
+		err := c.limiter.wait(req.Context())
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.last = time.Now()
+	c.metrics.Request()
+	n := atomic.AddInt64(c.requests, 1)
+	if n%requestLogInterval == 0 {
+		c.logger.Debug("requests made", "count", n)
+	}
+	var doer HTTPDoer = &c.Client
+	if c.doer != nil {
+		doer = c.doer
+	}
+	resp, err := doer.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	err = checkThrottle(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if resp.StatusCode >= 500 {
+		resp.Body.Close()
+		return nil, &StatusError{Status: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+func (c *Crawler) doInit(ctx context.Context) error {
+	r, err := c.get(ctx, c.start)
 	if err != nil {
 		return err
 	}
@@ -203,20 +739,72 @@ func (c *Crawler) doInit() error {
 		return err
 	}
 	c.magicStrings = ms
+	c.page = 0
+	// the form posts back to the page it came from, after any redirect
+	c.url = r.Request.URL.String()
+	if c.searchTerm != "" {
+		c.searchField, err = findSearchField(html)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (c *Crawler) doSearch() error {
+// findSearchField returns the name of the search form's title text field: the first text input whose name mentions "title", or failing that the first text input.
+func findSearchField(html []byte) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(html))
+	if err != nil {
+		return "", err
+	}
+	var first, titled string
+	doc.Find("input[type=text]").Each(func(i int, sel *goquery.Selection) {
+		name, _ := sel.Attr("name")
+		if first == "" {
+			first = name
+		}
+		if titled == "" && strings.Contains(strings.ToLower(name), "title") {
+			titled = name
+		}
+	})
+	if titled != "" {
+		return titled, nil
+	}
+	if first != "" {
+		return first, nil
+	}
+	return "", &ParseError{errors.New("the search form has no text field for a search term")}
+}
+
+// SearchTypes are the classification types a search can be limited to (see WithSearchTypes), in the order of the search form's checkboxes.
+var SearchTypes = []string{"feature", "serial"}
+
+// searchTypeFields maps each of SearchTypes to the checkbox fields of the search form that select it, and searchTypeValues to the value they are submitted with.
+var searchTypeFields = map[string][]string{
+	"feature": {"chklstType$0", "chklstType$2"},
+	"serial":  {"chklstType$3"},
+}
+var searchTypeValues = map[string]string{
+	"feature": "Feature",
+	"serial":  "Serial",
+}
+
+func (c *Crawler) doSearch(ctx context.Context) error {
 	ms := c.magicStrings
 	vals := make(map[string][]string)
 	for k, v := range ms {
 		vals[k] = v
 	}
-	vals["chklstType$0"] = []string{"Feature"}
-	vals["chklstType$2"] = []string{"Feature"}
-	vals["chklstType$3"] = []string{"Serial"}
+	for _, t := range c.searchTypes {
+		for _, field := range searchTypeFields[t] {
+			vals[field] = []string{searchTypeValues[t]}
+		}
+	}
+	if c.searchTerm != "" {
+		vals[c.searchField] = []string{c.searchTerm}
+	}
 	vals["btnSearch"] = []string{"Search"}
-	r, err := c.PostForm(c.url, vals)
+	r, err := c.postForm(ctx, c.url, vals)
 	if err != nil {
 		return err
 	}
@@ -231,17 +819,66 @@ func (c *Crawler) doSearch() error {
 	}
 	c.magicStrings = ms
 	c.url = r.Request.URL.String()
+	c.grid = html
+	c.page = 1
+	c.total, _ = parseTotal(html)
+	err = c.saveSession()
+	if err != nil {
+		c.logger.Warn("couldn't save session", "file", c.cookieFile, "error", err)
+	}
 	return nil
 }
 
-func (c *Crawler) requestPage(page int) error {
+// totalRe matches the count of results shown above the search result grid, e.g. "72,000 records found".
+var totalRe = regexp.MustCompile(`(?i)([0-9][0-9,]*)\s+(?:records?|results?|titles?)\s+found`)
+
+// parseTotal returns the total number of search results shown on a search result page. Its bool return value is false if the page doesn't show one.
+func parseTotal(html []byte) (int, bool) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(html))
+	if err != nil {
+		return 0, false
+	}
+	m := totalRe.FindStringSubmatch(doc.Text())
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.Replace(m[1], ",", "", -1))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// TotalResults returns the total number of results found by the Crawler's search. It's bool return value is false if the search hasn't been done yet or the total couldn't be found on the result page.
+func (c *Crawler) TotalResults() (int, bool) {
+	return c.total, c.total > 0
+}
+
+// CountResults runs the Crawler's search and returns the total number of results found (see TotalResults). It returns an error if the search fails or the total can't be found.
+func (c *Crawler) CountResults(ctx context.Context) (int, error) {
+	err := c.doInit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	err = c.doSearch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := c.TotalResults()
+	if !ok {
+		return 0, errors.New("the search result page didn't show a total")
+	}
+	return n, nil
+}
+
+func (c *Crawler) requestPage(ctx context.Context, page int) error {
 	vals := make(map[string][]string)
 	for k, v := range c.magicStrings {
 		vals[k] = v
 	}
 	vals["__EVENTTARGET"] = []string{"gvResult"}
 	vals["__EVENTARGUMENT"] = []string{fmt.Sprint("Page$", page)}
-	r, err := c.PostForm(c.url, vals)
+	r, err := c.postForm(ctx, c.url, vals)
 	if err != nil {
 		return err
 	}
@@ -250,19 +887,99 @@ func (c *Crawler) requestPage(page int) error {
 	if err != nil {
 		return err
 	}
+	if !hasResultGrid(html) {
+		return ErrSessionExpired
+	}
 	u := r.Request.URL.String()
 	if u != c.url {
-		msg := fmt.Sprintf("Post URL changed: %s (was: %s).", u, c.url)
-		return errors.New(msg)
+		if !samePage(u, c.url) {
+			msg := fmt.Sprintf("Post URL changed: %s (was: %s).", u, c.url)
+			return errors.New(msg)
+		}
+		// e.g. a redirect adding a trailing slash or a session parameter
+		c.logger.Debug("post URL changed", "url", u, "was", c.url)
+		c.url = u
 	}
 	ms, err := getMagicStrings(html)
 	if err != nil {
 		return err
 	}
 	c.magicStrings = ms
+	c.grid = html
+	c.page = page
+	return nil
+}
+
+// samePage returns true if the URLs a and b differ at most in their query, fragment, a trailing slash, or the case of their scheme and host, i.e. they name the same page.
+func samePage(a string, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) &&
+		strings.EqualFold(ua.Host, ub.Host) &&
+		strings.TrimSuffix(ua.Path, "/") == strings.TrimSuffix(ub.Path, "/")
+}
+
+// pagerButtons is the number of numbered page links the search result grid's pager shows. On pages 1 to 10 it links pages 1 to 10, then "..." for page 11; on pages 11 to 20 it links "..." for page 10, pages 11 to 20, then "..." for page 21; and so on.
+const pagerButtons = 10
+
+// pagePostbacks returns the pages that must be requested, in order, to reach the search result page target from page 1, given that a page can only be requested while the pager links it (see pagerButtons). It returns nil if target is page 1.
+func pagePostbacks(target int) []int {
+	var pages []int
+	cur := 1
+	for cur != target {
+		// the furthest page the pager links from cur
+		reach := (cur-1)/pagerButtons*pagerButtons + pagerButtons + 1
+		if target <= reach {
+			cur = target
+		} else {
+			cur = reach
+		}
+		pages = append(pages, cur)
+	}
+	return pages
+}
+
+// seek requests search result pages until the given page is the current one. It assumes the current page is page 1, as it is after a search.
+func (c *Crawler) seek(ctx context.Context, page int) error {
+	for _, p := range pagePostbacks(page) {
+		c.logger.Debug("seeking", "page", p)
+		err := c.requestPage(ctx, p)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// refresh starts a new session, after the old one has expired, and returns to the given search result page.
+func (c *Crawler) refresh(ctx context.Context, page int) error {
+	err := c.doInit(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.doSearch(ctx)
+	if err != nil {
+		return err
+	}
+	return c.seek(ctx, page)
+}
+
+// hasResultGrid returns true if html is a search result page.
+func hasResultGrid(html []byte) bool {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(html))
+	if err != nil {
+		return false
+	}
+	return doc.Find("#gvResult").Length() > 0
+}
+
+// checkResponse returns an error unless html is a title page. It returns ErrNoSuchRow if html is the search result page, ErrSessionExpired if it is a new search form, ErrBlockedPage if it isn't one of the site's forms at all, and otherwise a ParseError.
 func checkResponse(html []byte) error {
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(html))
 	if err != nil {
@@ -270,120 +987,248 @@ func checkResponse(html []byte) error {
 	}
 	title := doc.Find("#lblTitle").Text()
 	if title == "" {
+		if doc.Find("#gvResult").Length() > 0 {
+			// the postback gave the search page back, not a title
+			return ErrNoSuchRow
+		}
+		if doc.Find("#btnSearch").Length() > 0 {
+			// a fresh search form: the session is gone
+			return ErrSessionExpired
+		}
+		if doc.Find("#form1").Length() == 0 {
+			// e.g. a block page, which may not last
+			return ErrBlockedPage
+		}
 		err = errors.New("title is the empty string")
-		return err
+		return &ParseError{err}
 	}
 	return nil
 }
 
-func (c *Crawler) requestRow(row int) (*http.Response, error) {
+func (c *Crawler) requestRow(ctx context.Context, row int) (*http.Response, error) {
 	vals := make(map[string][]string)
 	for k, v := range c.magicStrings {
 		vals[k] = v
 	}
 	vals["__EVENTTARGET"] = []string{"gvResult"}
 	vals["__EVENTARGUMENT"] = []string{fmt.Sprint("Title$", row)}
-	resp, err := c.PostForm(c.url, vals)
+	resp, err := c.postForm(ctx, c.url, vals)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
+// fetchRow requests the given row of the current search result page and returns it as a Result.
+func (c *Crawler) fetchRow(ctx context.Context, page int, row int) (Result, error) {
+	resp, err := c.requestRow(ctx, row)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return Result{}, ErrNoSuchRow
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, &StatusError{Status: resp.StatusCode}
+	}
+	html, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Result{}, err
+	}
+	err = checkResponse(html)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{
+		URL:  resp.Request.URL.String(),
+		HTML: html,
+		Page: page,
+		Row:  row,
+	}
+	return result, nil
+}
+
 // Result is a type returned through a channel by the Crawl method of the Crawler type. It holds the HTML of a classification database title page.
 type Result struct {
-	URL  string // get-able URL of result page
-	HTML []byte // html of the result page
-	Page int // search result page the result was found on
-	Row  int // search result row the result was found on
+	URL   string // get-able URL of result page
+	HTML  []byte // html of the result page
+	Page  int    // search result page the result was found on
+	Row   int    // search result row the result was found on
+	Index int    // index of the result (counting from 1, as for NewJob)
+}
+
+// Filename returns the default name of the file the Result is written to, title-{page}-{row}.html.
+func (r Result) Filename() string {
+	return fmt.Sprintf("title-%v-%v.html", r.Page, r.Row)
 }
 
-// The Crawl method takes a Job and two channels. The results channel is sent results as they are crawled. The jobs channal is sent jobs in the case of an error or they are done.
-func (c *Crawler) Crawl(j Job, results chan<- Result, jobs chan<- Job) {
-	// log.Print("Worker: doInit().")
-	err := c.doInit()
+// RenderName executes t with the Result's Page, Row, Index (if zero, as given by ResultIndex with ResultsPerPage) and URL as fields, e.g. "title-{{.Page}}-{{.Row}}.html", and returns the output as a file name. It returns an error if t fails or renders an empty name.
+func (r Result) RenderName(t *template.Template) (string, error) {
+	if r.Index == 0 {
+		r.Index = ResultIndex(r.Page, r.Row, ResultsPerPage)
+	}
+	var buf bytes.Buffer
+	err := t.Execute(&buf, r)
 	if err != nil {
-		j.Error = err
+		return "", err
+	}
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		msg := fmt.Sprintf("template %q renders an empty name", t.Name())
+		return "", errors.New(msg)
+	}
+	return name, nil
+}
+
+// The Crawl method takes a Context, a Job and two channels. The results channel is sent results as they are crawled. The jobs channal is sent jobs in the case of an error or they are done. Rows for which the Crawler's skip predicate (see WithSkip) returns true are not fetched. A Job that has previously failed is retried only after the delay given by the Crawler's BackoffConfig. If the Crawler's session is already on the Job's search result page (as when the Job is handed back to the Crawler that failed on one of its rows), Crawl carries on from the Job's next row without searching again. The Job sent back on an error starts at the row that failed, not at the start of its range, so a retry never fetches a row that has already been sent as a Result. If the site's session expires mid-crawl, Crawl starts a new one and returns to its place. If ctx is cancelled or its deadline passes, the in-flight request is aborted and the Job is sent back with ctx.Err() as its Error.
+func (c *Crawler) Crawl(ctx context.Context, j Job, results chan<- Result, jobs chan<- Job) {
+	// fail records err, as a CrawlError, on the Job and sends it back
+	fail := func(stage string, err error) {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		c.metrics.Error()
+		j.Attempts = j.Attempts + 1
+		j.Error = &CrawlError{
+			Start:   j.start,
+			Stop:    j.stop,
+			Page:    j.page(c.perPage),
+			Row:     j.row(c.perPage),
+			Stage:   stage,
+			Attempt: j.Attempts,
+			Err:     err,
+		}
+		// a row failing leaves the session on its page, so the
+		// Job can carry on from the row without searching again
+		j.crawler = nil
+		if stage == StageRow {
+			j.crawler = c
+		}
 		jobs <- j
-		return
 	}
-	// log.Print("Worker: doSearch().")
-	err = c.doSearch()
+	j.crawler = nil
+	err := sleep(ctx, c.backoff.Delay(j.Attempts))
 	if err != nil {
-		j.Error = err
-		jobs <- j
+		fail(StageBackoff, err)
 		return
 	}
-	// log.Print("Worker: seeking...")
-	if j.page() > 1 {
-		if j.page() > 11 {
-			page := j.page()
-			for i := 11; i < page; i = i + 10 {
-				// log.Printf("Worker: Requesting page %v.", i)
-				err = c.requestPage(i)
-				if err != nil {
-					j.Error = err
-					jobs <- j
-					return
-				}
-			}
-			// log.Printf("Worker: Requesting page %v.", page)
-			err = c.requestPage(page)
+	if c.magicStrings != nil && c.page == j.page(c.perPage) {
+		c.logger.Debug("resuming on the same session", "job", j, "page", c.page)
+	} else if c.magicStrings != nil && c.page == 1 {
+		// e.g. a session restored by WithCookieFile, on the first
+		// page of the search's results
+		c.logger.Debug("carrying on the session from page 1", "job", j)
+		err = c.seek(ctx, j.page(c.perPage))
+		if errors.Is(err, ErrSessionExpired) {
+			c.logger.Info("session expired; starting a new one", "page", j.page(c.perPage))
+			err = c.refresh(ctx, j.page(c.perPage))
 			if err != nil {
-				j.Error = err
-				jobs <- j
+				fail(StageRefresh, err)
 				return
 			}
 		}
-	}
-	// log.Print("Worker: Starting crawl loop.")
-	done := false
-	for !done {
-		// log.Printf("Worker: Requesting page %v, row %v.", j.page(), j.row())
-		resp, err := c.requestRow(j.row())
 		if err != nil {
-			j.Error = err
-			jobs <- j
+			fail(StagePage, err)
 			return
 		}
-		html, err := ioutil.ReadAll(resp.Body)
-		defer resp.Body.Close()
+	} else {
+		c.logger.Debug("loading search form", "job", j)
+		err = c.doInit(ctx)
 		if err != nil {
-			j.Error = err
-			jobs <- j
+			fail(StageInit, err)
 			return
 		}
-		err = checkResponse(html)
+		c.logger.Debug("searching")
+		err = c.doSearch(ctx)
 		if err != nil {
-			j.Error = err
-			jobs <- j
+			fail(StageSearch, err)
+			return
+		}
+		err = c.seek(ctx, j.page(c.perPage))
+		if err != nil {
+			fail(StagePage, err)
+			return
+		}
+	}
+	c.logger.Debug("crawling rows", "page", j.page(c.perPage))
+	done := false
+	for !done {
+		if ctx.Err() != nil {
+			fail(StageRow, ctx.Err())
 			return
 		}
-		result := Result{
-			URL:  resp.Request.URL.String(),
-			HTML: html,
-			Page: j.page(),
-			Row:  j.row(),
+		page, row := j.page(c.perPage), j.row(c.perPage)
+		if c.skip == nil || !c.skip(page, row) {
+			c.logger.Debug("requesting row", "page", page, "row", row)
+			result, err := c.fetchRow(ctx, page, row)
+			if errors.Is(err, ErrSessionExpired) {
+				c.logger.Info("session expired; starting a new one", "page", page)
+				err = c.refresh(ctx, page)
+				if err != nil {
+					fail(StageRefresh, err)
+					return
+				}
+				result, err = c.fetchRow(ctx, page, row)
+			}
+			if err != nil {
+				fail(StageRow, err)
+				return
+			}
+			result.Index = j.start
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				// no one may be left to receive it
+				fail(StageRow, ctx.Err())
+				return
+			}
+			j.Error = nil
+			j.Attempts = 0
 		}
-		results <- result
-		oldPage := j.page()
+		oldPage := j.page(c.perPage)
 		j = j.next()
 		done = j.IsDone()
 		if done {
 			jobs <- j
 			return
 		}
-		newPage := j.page()
+		newPage := j.page(c.perPage)
 		needPage := oldPage != newPage
 		if needPage {
-			// log.Printf("Worker: Need page %v, requesting.", j.page())
-			err = c.requestPage(j.page())
+			c.logger.Debug("requesting page", "page", j.page(c.perPage))
+			err = c.requestPage(ctx, j.page(c.perPage))
+			if errors.Is(err, ErrSessionExpired) {
+				c.logger.Info("session expired; starting a new one", "page", j.page(c.perPage))
+				err = c.refresh(ctx, j.page(c.perPage))
+				if err != nil {
+					fail(StageRefresh, err)
+					return
+				}
+			}
 			if err != nil {
-				j.Error = err
-				jobs <- j
+				fail(StagePage, err)
 				return
 			}
 		}
 	}
 	// close channel?
 }
+
+// CrawlResults crawls the Job j once, as Crawl does, but collects the results in memory rather than sending them over a channel. It returns the results crawled, in order, and the error that stopped the Job, if any; the Job is not retried, so on error the results are those crawled before it.
+func (c *Crawler) CrawlResults(ctx context.Context, j Job) ([]Result, error) {
+	results := make(chan Result)
+	jobs := make(chan Job, 1)
+	go c.Crawl(ctx, j, results, jobs)
+	var out []Result
+	for {
+		select {
+		case r := <-results:
+			out = append(out, r)
+		case j = <-jobs:
+			// Crawl sends the Job back last, and results is
+			// unbuffered, so every result has been received.
+			return out, j.Error
+		}
+	}
+}
@@ -0,0 +1,1220 @@
+package libsuger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestJobPageRow(t *testing.T) {
+	tests := []struct {
+		perPage int
+		index   int
+		page    int
+		row     int
+	}{
+		{20, 1, 1, 0},
+		{20, 20, 1, 19},
+		{20, 21, 2, 0},
+		{20, 40, 2, 19},
+		{20, 41, 3, 0},
+		{10, 20, 2, 9},
+		{10, 21, 3, 0},
+		{10, 40, 4, 9},
+		{10, 41, 5, 0},
+		{25, 20, 1, 19},
+		{25, 21, 1, 20},
+		{25, 40, 2, 14},
+		{25, 41, 2, 15},
+		{1, 20, 20, 0},
+		{1, 21, 21, 0},
+	}
+	for _, tt := range tests {
+		j, err := NewJob(tt.index, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, row := j.page(tt.perPage), j.row(tt.perPage)
+		if page != tt.page || row != tt.row {
+			t.Errorf("result %v, %v per page: page %v row %v, want page %v row %v", tt.index, tt.perPage, page, row, tt.page, tt.row)
+		}
+		if i := ResultIndex(page, row, tt.perPage); i != tt.index {
+			t.Errorf("ResultIndex(%v, %v, %v) = %v, want %v", page, row, tt.perPage, i, tt.index)
+		}
+	}
+}
+
+func TestJobAccessors(t *testing.T) {
+	j, _ := NewJob(5, 10)
+	if j.Start() != 5 || j.Stop() != 15 || j.Count() != 10 {
+		t.Errorf("Start %v, Stop %v, Count %v, want 5, 15, 10", j.Start(), j.Stop(), j.Count())
+	}
+	if s := j.String(); s != "Job(results 5-14, 10 left)" {
+		t.Errorf("String() = %q", s)
+	}
+	j = j.next()
+	if j.Start() != 6 || j.Count() != 9 {
+		t.Errorf("after one result, Start %v, Count %v, want 6, 9", j.Start(), j.Count())
+	}
+	j.Attempts = 2
+	j.Error = errors.New("timeout")
+	if s := j.String(); s != "Job(results 6-14, 9 left) after 2 failed attempts: timeout" {
+		t.Errorf("failed Job's String() = %q", s)
+	}
+	for !j.IsDone() {
+		j = j.next()
+	}
+	if j.Count() != 0 || j.String() != "Job(done at 14)" {
+		t.Errorf("done Job has Count %v, String %q", j.Count(), j.String())
+	}
+}
+
+func TestNewJobBounds(t *testing.T) {
+	tests := []struct {
+		start int
+		count int
+		ok    bool
+	}{
+		{1, 1, true},
+		{1, MaxResults, true},       // every result
+		{MaxResults, 1, true},       // the last result
+		{MaxResults - 9, 10, true},  // up to the last result
+		{MaxResults - 9, 11, false}, // one past it
+		{1, MaxResults + 1, false},  // one more than there can be
+		{MaxResults + 1, 1, false},  // starting past the last result
+		{2, math.MaxInt, false},     // start+count would overflow
+		{math.MaxInt, math.MaxInt, false},
+		{0, 10, false},
+		{1, 0, false},
+		{-5, 10, false},
+		{1, -1, false},
+		{math.MinInt, 1, false},
+	}
+	for _, tt := range tests {
+		j, err := NewJob(tt.start, tt.count)
+		if (err == nil) != tt.ok {
+			t.Errorf("NewJob(%v, %v): got error %v, want ok %v", tt.start, tt.count, err, tt.ok)
+			continue
+		}
+		if err == nil && (j.Start() != tt.start || j.Count() != tt.count || j.Stop() <= j.Start()) {
+			t.Errorf("NewJob(%v, %v) = %v", tt.start, tt.count, j)
+		}
+	}
+	// the largest Job still partitions cleanly
+	j, _ := NewJob(1, MaxResults)
+	parts, err := j.Partition(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := j.Start()
+	for _, p := range parts {
+		if p.Start() != next || p.Count() < 1 {
+			t.Errorf("partition %v doesn't follow on from result %v", p, next)
+		}
+		next = p.Stop()
+	}
+	if next != j.Stop() {
+		t.Errorf("partitions stop at %v, want %v", next, j.Stop())
+	}
+}
+
+func TestJobPages(t *testing.T) {
+	j, _ := NewJob(20, 22) // results 20 to 41
+	first, last := j.Pages(20)
+	if first != 1 || last != 3 {
+		t.Errorf("pages %v to %v, want 1 to 3", first, last)
+	}
+	first, last = j.Pages(10)
+	if first != 2 || last != 5 {
+		t.Errorf("with 10 per page, pages %v to %v, want 2 to 5", first, last)
+	}
+}
+
+func TestWithResultsPerPage(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		_, err := NewCrawler(WithResultsPerPage(n))
+		if err == nil {
+			t.Errorf("WithResultsPerPage(%v) succeeded", n)
+		}
+	}
+	c, err := NewCrawler(WithResultsPerPage(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.perPage != 10 {
+		t.Errorf("perPage is %v, want 10", c.perPage)
+	}
+}
+
+func TestCrawlResultsPerPage(t *testing.T) {
+	site, srv := newFakeSite(t, 25)
+	site.PerPage = 10
+	var results []Result
+	_, err := CrawlRange(context.Background(), 9, 4, 1, storeResults(&results), WithBaseURL(srv.URL), WithResultsPerPage(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Search", "Title$8", "Title$9", "Page$2", "Title$0", "Title$1"}
+	if got := site.Events(); !equalStrings(got, want) {
+		t.Errorf("postbacks %q, want %q", got, want)
+	}
+	for i, r := range results {
+		if r.Index != 9+i {
+			t.Errorf("result %v has Index %v, want %v", i, r.Index, 9+i)
+		}
+	}
+}
+
+func TestCrawlRangeFakeSite(t *testing.T) {
+	site, srv := newFakeSite(t, 45)
+	var results []Result
+	sum, err := CrawlRange(context.Background(), 1, 45, 3, storeResults(&results), WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Results != 45 || sum.Failed != 0 || sum.Missing != 0 {
+		t.Errorf("summary %+v, want 45 results", sum)
+	}
+	if sum.Requests != len(site.Requests()) {
+		t.Errorf("summary counts %v requests, site got %v", sum.Requests, len(site.Requests()))
+	}
+	seen := make(map[int]bool)
+	for _, r := range results {
+		if seen[r.Index] {
+			t.Errorf("result %v crawled twice", r.Index)
+		}
+		seen[r.Index] = true
+		page, row := ResultPosition(r.Index, ResultsPerPage)
+		if r.Page != page || r.Row != row {
+			t.Errorf("result %v at page %v row %v, want page %v row %v", r.Index, r.Page, r.Row, page, row)
+		}
+		title, err := NewTitleFromHTML(r.HTML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title.Name != fakeName(r.Index) {
+			t.Errorf("result %v is %q, want %q", r.Index, title.Name, fakeName(r.Index))
+		}
+	}
+	if len(seen) != 45 {
+		t.Errorf("crawled %v distinct results, want 45", len(seen))
+	}
+	// each worker loads the search form, then searches
+	gets, searches := 0, 0
+	for _, r := range site.Requests() {
+		if r.Method == "GET" {
+			gets = gets + 1
+		}
+		if r.Event == "Search" {
+			searches = searches + 1
+			for _, field := range magicFields {
+				if r.Form.Get(field) == "" {
+					t.Errorf("search posted without %v", field)
+				}
+			}
+		}
+	}
+	if gets != 3 || searches != 3 {
+		t.Errorf("%v GETs and %v searches, want one each per worker", gets, searches)
+	}
+}
+
+func TestCrawlMissingRow(t *testing.T) {
+	// the search found 23 results, but the grid only has 22
+	site, srv := newFakeSite(t, 22)
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if r.Event == "Search" {
+			fmt.Fprint(w, strings.Replace(site.resultPage(1), "22 records", "23 records", 1))
+			return true
+		}
+		return false
+	}
+	var results []Result
+	sum, err := CrawlRange(context.Background(), 21, 3, 1, storeResults(&results), WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Results != 2 || sum.Missing != 1 {
+		t.Errorf("summary %+v, want 2 results and 1 missing", sum)
+	}
+}
+
+func TestPagePostbacks(t *testing.T) {
+	tests := []struct {
+		target int
+		want   []int
+	}{
+		{1, nil},
+		{2, []int{2}},
+		{10, []int{10}},
+		{11, []int{11}},
+		{12, []int{11, 12}},
+		{20, []int{11, 20}},
+		{21, []int{11, 21}},
+		{22, []int{11, 21, 22}},
+		{31, []int{11, 21, 31}},
+	}
+	for _, tt := range tests {
+		got := pagePostbacks(tt.target)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("pagePostbacks(%v) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlSeek(t *testing.T) {
+	// the fake site refuses a page its pager doesn't link, so a
+	// wrong seek fails the crawl
+	for _, target := range []int{1, 11, 12, 21, 31} {
+		site, srv := newFakeSite(t, 700)
+		c, err := NewCrawler(WithBaseURL(srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		j, _ := NewJob(ResultIndex(target, 0, ResultsPerPage), 1)
+		results, err := c.CrawlResults(context.Background(), j)
+		if err != nil {
+			t.Fatalf("page %v: %v", target, err)
+		}
+		if len(results) != 1 || results[0].Page != target {
+			t.Errorf("page %v: got %v results, want the first of page %v", target, len(results), target)
+		}
+		want := []string{"Search"}
+		for _, p := range pagePostbacks(target) {
+			want = append(want, fmt.Sprint("Page$", p))
+		}
+		want = append(want, "Title$0")
+		if got := site.Events(); !equalStrings(got, want) {
+			t.Errorf("page %v: postbacks %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestSamePage(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want bool
+	}{
+		{"http://suger.test/Search/", "http://suger.test/Search/", true},
+		{"http://suger.test/Search/?session=1", "http://suger.test/Search/", true},
+		{"http://suger.test/Search/#grid", "http://suger.test/Search/", true},
+		{"http://suger.test/Search", "http://suger.test/Search/", true},
+		{"HTTP://SUGER.test/Search/", "http://suger.test/Search/", true},
+		{"http://suger.test/search/", "http://suger.test/Search/", false},
+		{"http://suger.test/Error.aspx", "http://suger.test/Search/", false},
+		{"https://suger.test/Search/", "http://suger.test/Search/", false},
+		{"http://other.test/Search/", "http://suger.test/Search/", false},
+		{"http://suger.test:8080/Search/", "http://suger.test/Search/", false},
+		{"http://%zz/Search/", "http://suger.test/Search/", false},
+	}
+	for _, tt := range tests {
+		if got := samePage(tt.a, tt.b); got != tt.want {
+			t.Errorf("samePage(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRequestPageRedirect(t *testing.T) {
+	tests := []struct {
+		location string
+		ok       bool
+	}{
+		{searchPath + "?session=abc", true},
+		{strings.TrimSuffix(searchPath, "/"), true},
+		{"/Error.aspx", false},
+	}
+	for _, tt := range tests {
+		site, srv := newFakeSite(t, 45)
+		redirected := false
+		site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+			if r.Event == "Page$2" && !redirected {
+				redirected = true
+				w.Header().Set("Location", tt.location)
+				w.WriteHeader(http.StatusTemporaryRedirect)
+				return true
+			}
+			return false
+		}
+		c, err := NewCrawler(WithBaseURL(srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.doInit(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.doSearch(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.requestPage(context.Background(), 2)
+		if !tt.ok {
+			if err == nil || !strings.Contains(err.Error(), "Post URL changed") {
+				t.Errorf("redirect to %q: got error %v, want the post URL changed", tt.location, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("redirect to %q: %v", tt.location, err)
+			continue
+		}
+		// the crawl carries on from where it was redirected to
+		if want := srv.URL + tt.location; c.url != want || c.page != 2 {
+			t.Errorf("redirect to %q: on page %v of %q, want page 2 of %q", tt.location, c.page, c.url, want)
+		}
+		err = c.requestPage(context.Background(), 3)
+		if err != nil || c.page != 3 {
+			t.Errorf("redirect to %q: requesting page 3 after: %v", tt.location, err)
+		}
+	}
+}
+
+func TestCrawlResults(t *testing.T) {
+	site, srv := newFakeSite(t, 45)
+	c, err := NewCrawler(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, _ := NewJob(18, 6) // results 18 to 23, across pages 1 and 2
+	results, err := c.CrawlResults(context.Background(), j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("got %v results, want 6", len(results))
+	}
+	for i, r := range results {
+		title, err := NewTitleFromHTML(r.HTML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Index != 18+i || title.Name != fakeName(18+i) {
+			t.Errorf("result %v is %v, %q, want %v, %q", i, r.Index, title.Name, 18+i, fakeName(18+i))
+		}
+	}
+	want := []string{"Search", "Title$17", "Title$18", "Title$19", "Page$2", "Title$0", "Title$1", "Title$2"}
+	if got := site.Events(); !equalStrings(got, want) {
+		t.Errorf("postbacks %q, want %q", got, want)
+	}
+
+	// an error stops the Job, keeping the results crawled before it
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if r.Event == "Title$1" {
+			http.Error(w, "no", http.StatusInternalServerError)
+			return true
+		}
+		return false
+	}
+	c, err = NewCrawler(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, _ = NewJob(1, 5)
+	results, err = c.CrawlResults(context.Background(), j)
+	var se *StatusError
+	if !errors.As(err, &se) || se.Status != http.StatusInternalServerError {
+		t.Errorf("got error %v, want a 500 StatusError", err)
+	}
+	if len(results) != 1 || results[0].Index != 1 {
+		t.Errorf("got %v results, want result 1 only", len(results))
+	}
+}
+
+func TestCrawlSessionExpired(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   int    // the page the session expires on
+		event  string // and the postback it expires on
+		resume []string
+	}{
+		{"on a row", 2, "Title$3", []string{"Search", "Page$2", "Title$3"}},
+		{"on a page", 2, "Page$3", []string{"Search", "Page$3", "Title$0"}},
+	}
+	for _, tt := range tests {
+		site, srv := newFakeSite(t, 45)
+		expired := 0 // the request that expired
+		site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+			if expired == 0 && r.Page == tt.page && r.Event == tt.event {
+				expired = n
+				fmt.Fprint(w, fakeSearchForm)
+				return true
+			}
+			return false
+		}
+		c, err := NewCrawler(WithBaseURL(srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		j, _ := NewJob(1, 45)
+		results, err := c.CrawlResults(context.Background(), j)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(results) != 45 {
+			t.Errorf("%s: got %v results, want 45", tt.name, len(results))
+		}
+		for i, r := range results {
+			if r.Index != i+1 {
+				t.Errorf("%s: result %v has Index %v", tt.name, i, r.Index)
+			}
+		}
+		if expired == 0 {
+			t.Fatalf("%s: the session never expired", tt.name)
+		}
+		// a new session, searched and sought back to where it was
+		reqs := site.Requests()[expired:]
+		if len(reqs) < len(tt.resume)+1 || reqs[0].Method != "GET" {
+			t.Fatalf("%s: didn't start a new session after it expired", tt.name)
+		}
+		var got []string
+		for _, r := range reqs[1 : len(tt.resume)+1] {
+			got = append(got, r.Event)
+		}
+		if !equalStrings(got, tt.resume) {
+			t.Errorf("%s: after the session expired, postbacks %q, want %q", tt.name, got, tt.resume)
+		}
+	}
+}
+
+func TestCrawlResumeMidPartition(t *testing.T) {
+	// results 1 to 30, pages 1 and 2, fail on result 25 (page 2, row 4)
+	// this many times running
+	for _, failures := range []int{1, 2, 3} {
+		site, srv := newFakeSite(t, 45)
+		failed := 0
+		site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+			if r.Page == 2 && r.Event == "Title$4" && failed < failures {
+				failed = failed + 1
+				http.Error(w, "no", http.StatusInternalServerError)
+				return true
+			}
+			return false
+		}
+		var results []Result
+		sum, err := CrawlRange(context.Background(), 1, 30, 1, storeResults(&results), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}))
+		if err != nil {
+			t.Fatalf("%v failures: %v", failures, err)
+		}
+		if sum.Results != 30 || sum.Retries != failures {
+			t.Errorf("%v failures: summary %+v, want 30 results after %v retries", failures, sum, failures)
+		}
+		got := make(map[int]int)
+		for _, r := range results {
+			got[r.Index] = got[r.Index] + 1
+		}
+		for i := 1; i <= 30; i++ {
+			if got[i] != 1 {
+				t.Errorf("%v failures: result %v stored %v times", failures, i, got[i])
+			}
+		}
+
+		// every attempt resumes from the failed row: the first on the
+		// same session, and later ones on a new session, which seeks
+		// straight to its page
+		want := []string{"Search"}
+		for row := 0; row < 20; row++ {
+			want = append(want, fmt.Sprint("Title$", row))
+		}
+		want = append(want, "Page$2", "Title$0", "Title$1", "Title$2", "Title$3", "Title$4", "Title$4")
+		for i := 2; i <= failures; i++ {
+			want = append(want, "Search", "Page$2", "Title$4")
+		}
+		want = append(want, "Title$5", "Title$6", "Title$7", "Title$8", "Title$9")
+		if got := site.Events(); !equalStrings(got, want) {
+			t.Errorf("%v failures: postbacks %q, want %q", failures, got, want)
+		}
+	}
+}
+
+func TestCrawlNoRowFetchedTwice(t *testing.T) {
+	site, srv := newFakeSite(t, 60)
+	var mu sync.Mutex
+	fetched := make(map[int]int)   // times each result's title page was served
+	failed := make(map[string]int) // times each postback has been failed
+	// between them, the errors fail Jobs after they have stored some
+	// results, at a page as well as a row, and more than once running,
+	// so that a fresh Crawler takes over
+	fail := map[string]int{"2/Page$3": 1, "2/Title$7": 1, "1/Title$12": 3}
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		key := fmt.Sprint(r.Page, "/", r.Event)
+		if failed[key] < fail[key] {
+			failed[key] = failed[key] + 1
+			http.Error(w, "no", http.StatusInternalServerError)
+			return true
+		}
+		if strings.HasPrefix(r.Event, "Title$") {
+			row, _ := strconv.Atoi(strings.TrimPrefix(r.Event, "Title$"))
+			index := ResultIndex(r.Page, row, ResultsPerPage)
+			fetched[index] = fetched[index] + 1
+		}
+		return false
+	}
+	var results []Result
+	sum, err := CrawlRange(context.Background(), 1, 60, 2, storeResults(&results), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Results != 60 || sum.Retries != 5 {
+		t.Errorf("summary %+v, want 60 results after 5 retries", sum)
+	}
+	stored := make(map[int]int)
+	for _, r := range results {
+		stored[r.Index] = stored[r.Index] + 1
+	}
+	for i := 1; i <= 60; i++ {
+		if fetched[i] != 1 || stored[i] != 1 {
+			t.Errorf("result %v fetched %v times and stored %v times, want once", i, fetched[i], stored[i])
+		}
+	}
+}
+
+func TestResultFilenameIndex(t *testing.T) {
+	_, srv := newFakeSite(t, 45)
+	c, err := NewCrawler(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, _ := NewJob(19, 4) // the last two rows of page 1, the first two of page 2
+	results, err := c.CrawlResults(context.Background(), j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		index int
+		file  string
+	}{
+		{19, "title-1-18.html"},
+		{20, "title-1-19.html"},
+		{21, "title-2-0.html"},
+		{22, "title-2-1.html"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %v results, want %v", len(results), len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Index != w.index || r.Filename() != w.file {
+			t.Errorf("result %v: Index %v, Filename %q, want %v, %q", i, r.Index, r.Filename(), w.index, w.file)
+		}
+		if ResultIndex(r.Page, r.Row, ResultsPerPage) != r.Index {
+			t.Errorf("result %v: page %v row %v doesn't agree with Index %v", i, r.Page, r.Row, r.Index)
+		}
+	}
+}
+
+func TestRenderName(t *testing.T) {
+	r := Result{URL: "https://app.mda.gov.sg/Classification/Search/Film/SearchDetail.aspx?sRowID=ROW000042", Page: 3, Row: 1, Index: 42}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"title-{{.Page}}-{{.Row}}.html", "title-3-1.html"},
+		{`{{printf "%06d" .Index}}.html`, "000042.html"},
+		{"page-{{.Page}}/{{.Row}}.html", "page-3/1.html"},
+		{"  {{.Index}}.htm\n", "42.htm"},
+	}
+	for _, tt := range tests {
+		tmpl := template.Must(template.New("name").Parse(tt.text))
+		got, err := r.RenderName(tmpl)
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q renders %q, want %q", tt.text, got, tt.want)
+		}
+	}
+	// with no Index, it is worked out from the Page and Row
+	tmpl := template.Must(template.New("name").Parse("{{.Index}}"))
+	got, err := Result{Page: 2, Row: 0}.RenderName(tmpl)
+	if err != nil || got != "21" {
+		t.Errorf("without an Index, rendered %q (%v), want 21", got, err)
+	}
+	for _, text := range []string{"", "  ", "{{.Missing}}", "{{if false}}x{{end}}"} {
+		tmpl := template.Must(template.New("name").Option("missingkey=error").Parse(text))
+		if _, err := r.RenderName(tmpl); err == nil {
+			t.Errorf("%q rendered a name", text)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	tests := []struct {
+		start int
+		count int
+		n     int
+		parts int
+	}{
+		{1, 5, 8, 5},   // count < n: one result each
+		{1, 1, 3, 1},   // count < n
+		{1, 8, 8, 8},   // count == n
+		{1, 20, 4, 4},  // evenly divisible
+		{1, 22, 4, 4},  // unevenly divisible
+		{7, 23, 5, 5},  // unevenly, not from 1
+		{100, 3, 1, 1}, // one partition
+	}
+	for _, tt := range tests {
+		j, err := NewJob(tt.start, tt.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts, err := j.Partition(tt.n)
+		if err != nil {
+			t.Fatalf("%v.Partition(%v): %v", j, tt.n, err)
+		}
+		if len(parts) != tt.parts {
+			t.Errorf("%v.Partition(%v) made %v partitions, want %v", j, tt.n, len(parts), tt.parts)
+		}
+		total := 0
+		for _, p := range parts {
+			total = total + p.Count()
+		}
+		if total != tt.count {
+			t.Errorf("%v.Partition(%v) has %v results, want %v", j, tt.n, total, tt.count)
+		}
+	}
+	j, _ := NewJob(1, 5)
+	for _, n := range []int{0, -1} {
+		_, err := j.Partition(n)
+		if err == nil {
+			t.Errorf("Partition(%v) succeeded", n)
+		}
+	}
+}
+
+// checkPartitions checks that parts cover exactly the results of j, in order, with no gaps or overlaps, and that their sizes differ by at most one, the larger first.
+func checkPartitions(t *testing.T, j Job, parts []Job) {
+	t.Helper()
+	var covered []int
+	for _, p := range parts {
+		if p.Count() < 1 {
+			t.Errorf("%v: empty partition %v", j, p)
+		}
+		for i := p.Start(); i < p.Stop(); i++ {
+			covered = append(covered, i)
+		}
+	}
+	if len(covered) != j.Count() {
+		t.Errorf("%v: partitions cover %v results, want %v", j, len(covered), j.Count())
+	}
+	for i, index := range covered {
+		if index != j.Start()+i {
+			t.Errorf("%v: partitions cover result %v where %v was due (gap or overlap)", j, index, j.Start()+i)
+			break
+		}
+	}
+	for i := 1; i < len(parts); i++ {
+		d := parts[i-1].Count() - parts[i].Count()
+		if d < 0 || d > 1 {
+			t.Errorf("%v: partition %v has %v results after one of %v", j, i, parts[i].Count(), parts[i-1].Count())
+		}
+	}
+}
+
+func TestPartitionCoversRange(t *testing.T) {
+	for _, start := range []int{1, 19, 1000} {
+		for count := 1; count <= 50; count++ {
+			for n := 1; n <= 12; n++ {
+				j, _ := NewJob(start, count)
+				parts, err := j.Partition(n)
+				if err != nil {
+					t.Fatalf("%v.Partition(%v): %v", j, n, err)
+				}
+				checkPartitions(t, j, parts)
+			}
+		}
+	}
+}
+
+// readFixture scrapes the title page testdata/name, saved from the site.
+func readFixture(t *testing.T, name string) *Title {
+	t.Helper()
+	html, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := NewTitleFromHTML(html)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return title
+}
+
+func TestPartitionBySize(t *testing.T) {
+	tests := []struct {
+		start int
+		count int
+		size  int
+		want  []int // results in each partition
+	}{
+		{1, 20, 5, []int{5, 5, 5, 5}},    // exact
+		{1, 22, 5, []int{5, 5, 5, 5, 2}}, // a remainder
+		{7, 23, 10, []int{10, 10, 3}},    // a remainder, not from 1
+		{1, 3, 100, []int{3}},            // one partition
+		{1, 3, 1, []int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		j, _ := NewJob(tt.start, tt.count)
+		parts, err := j.PartitionBySize(tt.size)
+		if err != nil {
+			t.Fatalf("%v.PartitionBySize(%v): %v", j, tt.size, err)
+		}
+		var got []int
+		next := j.Start()
+		for _, p := range parts {
+			got = append(got, p.Count())
+			if p.Start() != next {
+				t.Errorf("%v.PartitionBySize(%v): partition %v starts at %v, want %v", j, tt.size, p, p.Start(), next)
+			}
+			next = p.Stop()
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) || next != j.Stop() {
+			t.Errorf("%v.PartitionBySize(%v) = %v, want sizes %v", j, tt.size, parts, tt.want)
+		}
+	}
+	j, _ := NewJob(1, 5)
+	for _, size := range []int{0, -1} {
+		_, err := j.PartitionBySize(size)
+		if err == nil {
+			t.Errorf("PartitionBySize(%v) succeeded", size)
+		}
+	}
+}
+
+func TestCrawlChunkSize(t *testing.T) {
+	_, srv := newFakeSite(t, 45)
+	var results []Result
+	// more chunks than workers, so the workers take turns at them
+	sum, err := CrawlRange(context.Background(), 3, 25, 2, storeResults(&results), WithBaseURL(srv.URL), WithChunkSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[int]int)
+	for _, r := range results {
+		got[r.Index] = got[r.Index] + 1
+	}
+	if sum.Results != 25 || len(got) != 25 {
+		t.Errorf("stored %v results, %v different, want 25", sum.Results, len(got))
+	}
+	for i := 3; i < 28; i++ {
+		if got[i] != 1 {
+			t.Errorf("result %v stored %v times", i, got[i])
+		}
+	}
+	_, err = NewCrawler(WithChunkSize(-1))
+	if err == nil {
+		t.Error("a negative chunk size was accepted")
+	}
+}
+
+func TestDistributor(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		distributor string
+	}{
+		{"feature.html", "NEW VIDEO"},
+		{"legacy-ra.html", ""}, // "N/A"
+	}
+	for _, tt := range tests {
+		title := readFixture(t, tt.fixture)
+		if title.Distributor != tt.distributor {
+			t.Errorf("%s: Distributor %q, want %q", tt.fixture, title.Distributor, tt.distributor)
+		}
+	}
+}
+
+func TestRunningTime(t *testing.T) {
+	tests := []struct {
+		fixture string
+		minutes int
+	}{
+		{"feature.html", 363},
+		{"legacy-ra.html", 0}, // given as "0"
+	}
+	for _, tt := range tests {
+		title := readFixture(t, tt.fixture)
+		if title.RunningTime != tt.minutes {
+			t.Errorf("%s: RunningTime %v, want %v", tt.fixture, title.RunningTime, tt.minutes)
+		}
+		if title.Duration() != time.Duration(tt.minutes)*time.Minute {
+			t.Errorf("%s: Duration %v, want %v minutes", tt.fixture, title.Duration(), tt.minutes)
+		}
+	}
+	for s, want := range map[string]int{"120": 120, "120 mins": 120, "N/A": 0, "": 0, "-": 0} {
+		if got := parseMinutes(s); got != want {
+			t.Errorf("parseMinutes(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tests := map[string]string{
+		"SearchDetail.aspx?sType=Feature&sRowID=AAAH4UAAPAAABBpAAI":                                                  "AAAH4UAAPAAABBpAAI",
+		"SearchDetail.aspx?sType=Feature&sRowID=AAAH4UAAPAAADw%2bAAO":                                                "AAAH4UAAPAAADw+AAO",
+		"https://app.mda.gov.sg/Classification/Search/Film/SearchDetail.aspx?sRowID=AAAH4UAAPAAAA50AAd&sType=Serial": "AAAH4UAAPAAAA50AAd",
+		"SearchDetail.aspx?sType=Feature":                                                                            "",
+		"":                                                                                                           "",
+		"%zz":                                                                                                        "",
+	}
+	for u, want := range tests {
+		if got := parseID(u); got != want {
+			t.Errorf("parseID(%q) = %q, want %q", u, got, want)
+		}
+	}
+	// from the form's action, with its escaped "+"
+	title := readFixture(t, "feature.html")
+	if title.ID != "AAAH4UAAPAAADw+AAO" {
+		t.Errorf("ID %q, want AAAH4UAAPAAADw+AAO", title.ID)
+	}
+}
+
+func TestHasRatingRatingCount(t *testing.T) {
+	title := &Title{Ratings: []Rating{
+		{Rating: "Parental Guidance", Decision: "Passed Clean"},
+		{Rating: "Parental Guidance", Decision: "Passed With Cuts"}, // the same rating again, for another format
+		{Rating: "Matured Above 18", Decision: "Passed Clean"},
+		{Rating: "Matured Above 18", Decision: "Passed Clean"},
+		{Rating: "", Decision: "Banned"}, // no rating
+	}}
+	if n := title.RatingCount(); n != 2 {
+		t.Errorf("RatingCount() = %v, want 2", n)
+	}
+	for r, want := range map[string]bool{
+		"Parental Guidance":    true,
+		"Matured Above 18":     true,
+		"Restricted 21":        false,
+		"Parental Guidance 13": false,
+		"":                     false,
+	} {
+		if got := title.HasRating(r); got != want {
+			t.Errorf("HasRating(%q) = %v, want %v", r, got, want)
+		}
+	}
+	if n := (&Title{}).RatingCount(); n != 0 {
+		t.Errorf("RatingCount() of no ratings = %v, want 0", n)
+	}
+}
+
+func TestSetRatingOrder(t *testing.T) {
+	defer SetRatingOrder(DefaultRatingOrder)
+	title := &Title{Ratings: []Rating{
+		{Rating: "Parental Guidance 13", Decision: "Passed Clean"},
+		{Rating: "No Children Under 16", Decision: "Passed Clean"},
+		{Rating: "Restricted 30", Decision: "Passed Clean"},
+	}}
+	if max, _ := title.MaxRating(); max != "No Children Under 16" {
+		t.Errorf("default order: MaxRating() = %q, want No Children Under 16", max)
+	}
+
+	SetRatingOrder([]string{"Parental Guidance 13", "No Children Under 16"})
+	if max, _ := title.MaxRating(); max != "Parental Guidance 13" {
+		t.Errorf("reordered: MaxRating() = %q, want Parental Guidance 13", max)
+	}
+	if min, _ := title.MinRating(); min != "No Children Under 16" {
+		t.Errorf("reordered: MinRating() = %q, want No Children Under 16", min)
+	}
+
+	// a newly introduced rating
+	order := append([]string{"Restricted 30"}, DefaultRatingOrder...)
+	SetRatingOrder(order)
+	if max, _ := title.MaxRating(); max != "Restricted 30" {
+		t.Errorf("with a new rating: MaxRating() = %q, want Restricted 30", max)
+	}
+	// the order set is copied, and RatingOrder returns a copy
+	order[0] = "changed"
+	got := RatingOrder()
+	got[1] = "changed"
+	if r := RatingOrder(); r[0] != "Restricted 30" || r[1] != "Restricted 21" {
+		t.Errorf("RatingOrder() = %q after changing copies", r)
+	}
+
+	SetRatingOrder(DefaultRatingOrder)
+	if max, _ := title.MaxRating(); max != "No Children Under 16" {
+		t.Errorf("restored: MaxRating() = %q, want No Children Under 16", max)
+	}
+}
+
+func TestNormalizeRating(t *testing.T) {
+	tests := []struct {
+		rating string
+		want   string
+	}{
+		{"Restricted 21", "Restricted 21"},
+		{" restricted  21 ", "Restricted 21"},
+		{"MATURED ABOVE\n\t18", "Matured Above 18"},
+		{"parental guidance 13", "Parental Guidance 13"},
+		{"pg/ra", "PG/RA"},
+		{"  Pending   Review ", "Pending Review"}, // unrecognized, but tidied
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRating(tt.rating); got != tt.want {
+			t.Errorf("NormalizeRating(%q) = %q, want %q", tt.rating, got, tt.want)
+		}
+	}
+}
+
+func TestRawRating(t *testing.T) {
+	html := titlePage("ID1", "MESSY ALT TEXT",
+		Rating{Rating: "  parental guidance ", Decision: "Passed Clean"},
+		Rating{Rating: "MATURED\n  ABOVE 18", Decision: "Passed With Cuts"},
+		Rating{Rating: "General  Viewing", Decision: "Passed Clean"})
+	title, err := NewTitleFromHTML([]byte(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rating{
+		{Rating: "Parental Guidance", Decision: "Passed Clean", RawRating: "  parental guidance "},
+		{Rating: "Matured Above 18", Decision: "Passed With Cuts", RawRating: "MATURED\n  ABOVE 18"},
+		{Rating: "General Viewing", Decision: "Passed Clean", RawRating: "General  Viewing"},
+	}
+	if len(title.Ratings) != len(want) {
+		t.Fatalf("got ratings %+v, want %+v", title.Ratings, want)
+	}
+	for i := range want {
+		if title.Ratings[i] != want[i] {
+			t.Errorf("rating %v: got %+v, want %+v", i, title.Ratings[i], want[i])
+		}
+	}
+	max, ok := title.MaxRating()
+	if !ok || max != "Matured Above 18" {
+		t.Errorf("MaxRating() = %q, %v, want Matured Above 18", max, ok)
+	}
+	if !title.HasRating("Parental Guidance") {
+		t.Errorf("HasRating(%q) is false", "Parental Guidance")
+	}
+
+	// a Title scraped before ratings were normalized still matches
+	old := &Title{Ratings: []Rating{{Rating: " restricted  21"}}}
+	if max, ok := old.MaxRating(); !ok || max != "Restricted 21" {
+		t.Errorf("unnormalized: MaxRating() = %q, %v, want Restricted 21", max, ok)
+	}
+}
+
+func TestLegacyMaxRating(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{"legacy-ra.html", "RA"},
+		{"legacy-pgra.html", "PG/RA"},
+		{"refused-nar.html", "NAR"},
+	}
+	for _, tt := range tests {
+		title := readFixture(t, tt.fixture)
+		max, ok := title.MaxRating()
+		if !ok || max != tt.want {
+			t.Errorf("%s: MaxRating() = %q, %v, want %q", tt.fixture, max, ok, tt.want)
+		}
+		min, ok := title.MinRating()
+		if !ok || min != tt.want {
+			t.Errorf("%s: MinRating() = %q, %v, want %q", tt.fixture, min, ok, tt.want)
+		}
+	}
+
+	// any current rating outranks every legacy one
+	title := &Title{Ratings: []Rating{{Rating: "NAR"}, {Rating: "General Viewing"}, {Rating: "RA"}}}
+	if max, _ := title.MaxRating(); max != "General Viewing" {
+		t.Errorf("MaxRating() = %q, want General Viewing", max)
+	}
+	if min, _ := title.MinRating(); min != "General Viewing" {
+		t.Errorf("MinRating() = %q, want General Viewing", min)
+	}
+
+	defer SetLegacyRatingOrder(DefaultLegacyRatingOrder)
+	title = &Title{Ratings: []Rating{{Rating: "RA"}, {Rating: "PG/RA"}}}
+	if max, _ := title.MaxRating(); max != "RA" {
+		t.Errorf("MaxRating() = %q, want RA", max)
+	}
+	SetLegacyRatingOrder([]string{"PG/RA", "RA"})
+	if max, _ := title.MaxRating(); max != "PG/RA" {
+		t.Errorf("reordered: MaxRating() = %q, want PG/RA", max)
+	}
+	if got := LegacyRatingOrder(); !equalStrings(got, []string{"PG/RA", "RA"}) {
+		t.Errorf("LegacyRatingOrder() = %q", got)
+	}
+	SetLegacyRatingOrder(nil)
+	if max, ok := title.MaxRating(); ok {
+		t.Errorf("with no legacy ratings: MaxRating() = %q, true", max)
+	}
+}
+
+func TestTitleType(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{"feature.html", "feature"},
+		{"legacy-ra.html", "feature"},
+		{"serial.html", "serial"},
+	}
+	for _, tt := range tests {
+		title := readFixture(t, tt.fixture)
+		if title.Type != tt.want {
+			t.Errorf("%s: Type %q, want %q", tt.fixture, title.Type, tt.want)
+		}
+	}
+
+	// types suger doesn't search for, or none at all
+	page := titlePage("ID1", "A TITLE", Rating{Rating: "General", Decision: "Passed Clean"})
+	for action, want := range map[string]string{
+		"SearchDetail.aspx?sType=SERIAL&amp;sRowID=ID1":  "serial",
+		"SearchDetail.aspx?sType=Trailer&amp;sRowID=ID1": "trailer",
+		"SearchDetail.aspx?sType=+Video+&amp;sRowID=ID1": "video",
+		"SearchDetail.aspx?sRowID=ID1":                   "",
+		"SearchDetail.aspx?sType=&amp;sRowID=ID1":        "",
+	} {
+		html := strings.Replace(page, "SearchDetail.aspx?sType=Feature&amp;sRowID=ID1", action, 1)
+		title, err := NewTitleFromHTML([]byte(html))
+		if err != nil {
+			t.Errorf("%s: %v", action, err)
+			continue
+		}
+		if title.Type != want || title.ID != "ID1" {
+			t.Errorf("%s: Type %q, ID %q, want %q, ID1", action, title.Type, title.ID, want)
+		}
+	}
+}
+
+func TestMaxRatingDetail(t *testing.T) {
+	m18Clean := Rating{Rating: "Matured Above 18", Decision: "Passed Clean"}
+	m18Cuts := Rating{Rating: "Matured Above 18", Decision: "Passed With Cuts"}
+	m18Edited := Rating{Rating: "Matured Above 18", Decision: "Passed Clean (Edited)"}
+	pgCuts := Rating{Rating: "Parental Guidance", Decision: "Passed With Cuts"}
+	tests := []struct {
+		name    string
+		ratings []Rating
+		want    Rating
+	}{
+		{"clean then cut", []Rating{m18Clean, pgCuts, m18Cuts}, m18Cuts},
+		{"cut then clean", []Rating{m18Cuts, m18Clean}, m18Cuts},
+		{"edited and clean", []Rating{m18Clean, m18Edited}, m18Edited},
+		{"cuts and edited", []Rating{m18Edited, m18Cuts}, m18Cuts},
+		{"only clean", []Rating{pgCuts, m18Clean}, m18Clean},
+		{"spelled differently", []Rating{m18Clean, {Rating: " matured above  18", Decision: "Passed With Cuts"}}, Rating{Rating: " matured above  18", Decision: "Passed With Cuts"}},
+	}
+	for _, tt := range tests {
+		title := &Title{Ratings: tt.ratings}
+		got, ok := title.MaxRatingDetail()
+		if !ok || got != tt.want {
+			t.Errorf("%s: MaxRatingDetail() = %+v, %v, want %+v", tt.name, got, ok, tt.want)
+		}
+		if max, _ := title.MaxRating(); max != "Matured Above 18" {
+			t.Errorf("%s: MaxRating() = %q", tt.name, max)
+		}
+	}
+	got, ok := (&Title{Ratings: []Rating{{Rating: "Pending"}}}).MaxRatingDetail()
+	if ok || got != (Rating{}) {
+		t.Errorf("unrated: MaxRatingDetail() = %+v, %v, want the zero Rating", got, ok)
+	}
+}
+
+func TestAdvice(t *testing.T) {
+	tests := []struct {
+		fixture string
+		advice  []string
+	}{
+		{"feature.html", []string{"Some Violence"}},
+		{"advice-bilingual.html", []string{"Some Mature Content 内容只宜十六岁及以上"}}, // on two lines
+		{"refused-nar.html", []string{"Film is prohibited under Section 35(1) of the Films Act."}},
+		{"legacy-ra.html", nil}, // "-"
+	}
+	for _, tt := range tests {
+		title := readFixture(t, tt.fixture)
+		if !equalStrings(title.Advice, tt.advice) {
+			t.Errorf("%s: Advice %q, want %q", tt.fixture, title.Advice, tt.advice)
+		}
+		data, err := json.Marshal(title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(data), `"Advice":`); got != (tt.advice != nil) {
+			t.Errorf("%s: Advice in JSON is %v, want %v", tt.fixture, got, tt.advice != nil)
+		}
+	}
+}
+
+func TestTitleString(t *testing.T) {
+	tests := []struct {
+		title *Title
+		want  string
+	}{
+		{
+			&Title{Name: "SAMURAI (2012)", URL: "https://example.com/1", Ratings: []Rating{
+				{Rating: "Parental Guidance", Decision: "Passed Clean"},
+				{Rating: "Restricted 21", Decision: "Passed With Cuts"},
+			}},
+			`Title("SAMURAI (2012)", Restricted 21, 2 ratings, https://example.com/1)`,
+		},
+		{&Title{Name: "UNRATED", URL: "https://example.com/2"}, `Title("UNRATED", no rating, 0 ratings, https://example.com/2)`},
+		{&Title{Name: "TWO\nLINES"}, `Title("TWO\nLINES", no rating, 0 ratings, )`},
+	}
+	for _, tt := range tests {
+		if got := tt.title.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+		if strings.Contains(tt.title.String(), "\n") {
+			t.Errorf("String() of %q spans lines", tt.title.Name)
+		}
+	}
+}
+
+func TestCrawlerRequests(t *testing.T) {
+	site, srv := newFakeSite(t, 150)
+	// a failed request counts as much as any other
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if n == 50 {
+			http.Error(w, "busy", http.StatusInternalServerError)
+			return true
+		}
+		return false
+	}
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	sum, err := CrawlRange(context.Background(), 1, 150, 2, storeResults(new([]Result)), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := len(site.Requests())
+	if sum.Requests != hits {
+		t.Errorf("counted %v requests, the site got %v", sum.Requests, hits)
+	}
+	if sum.Retries != 1 {
+		t.Errorf("%v retries, want 1", sum.Retries)
+	}
+	if !strings.Contains(logged.String(), "msg=\"requests made\" count=100") {
+		t.Errorf("the count wasn't logged at 100 requests")
+	}
+
+	c, err := NewCrawler(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Requests() != 0 {
+		t.Errorf("a new Crawler has made %v requests", c.Requests())
+	}
+	j, _ := NewJob(1, 3)
+	_, err = c.CrawlResults(context.Background(), j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Requests() != len(site.Requests())-hits {
+		t.Errorf("Crawler counted %v requests, the site got %v", c.Requests(), len(site.Requests())-hits)
+	}
+}
@@ -0,0 +1,108 @@
+package libsuger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ListEntry is a title as listed in a row of the search result grid, found without requesting its title page.
+type ListEntry struct {
+	Name  string
+	Page  int    // search result page (from 1)
+	Row   int    // row on the page (from 0), as for Result
+	Index int    // index of the result (counting from 1, as for NewJob)
+	Link  string // the postback argument that opens the title page, e.g. "Title$3"
+}
+
+// postbackRe matches the target and argument of an ASP.NET postback link, e.g. "javascript:__doPostBack('gvResult','Title$3')".
+var postbackRe = regexp.MustCompile(`__doPostBack\('([^']*)','([^']*)'\)`)
+
+// ParseResultGrid returns the titles listed in the search result grid (#gvResult) of html, which is the given search result page, given perPage results per page. It returns ErrSessionExpired if html has no grid.
+func ParseResultGrid(html []byte, page int, perPage int) ([]ListEntry, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+	grid := doc.Find("#gvResult")
+	if grid.Length() == 0 {
+		return nil, ErrSessionExpired
+	}
+	var entries []ListEntry
+	grid.Find("a").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		m := postbackRe.FindStringSubmatch(href)
+		if m == nil || !strings.HasPrefix(m[2], "Title$") {
+			// e.g. a pager link, "Page$2"
+			return
+		}
+		row, err := strconv.Atoi(strings.TrimPrefix(m[2], "Title$"))
+		if err != nil {
+			return
+		}
+		entries = append(entries, ListEntry{
+			Name:  strings.Join(strings.Fields(s.Text()), " "),
+			Page:  page,
+			Row:   row,
+			Index: ResultIndex(page, row, perPage),
+			Link:  m[2],
+		})
+	})
+	return entries, nil
+}
+
+// ListTitles runs the Crawler's search and calls fn with each title listed on the search result pages from first to last (counting from 1), reading only the result grid, not each title page: one request per page rather than one per title. If last is zero, it lists to the last page, as worked out from TotalResults. If the site's session expires, ListTitles starts a new one and returns to its place. It stops at the first error, including one returned by fn.
+func (c *Crawler) ListTitles(ctx context.Context, first int, last int, fn func(ListEntry) error) error {
+	if first < 1 {
+		msg := fmt.Sprintf("first page (%v) must be at least 1.", first)
+		return errors.New(msg)
+	}
+	err := c.doInit(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.doSearch(ctx)
+	if err != nil {
+		return err
+	}
+	if last == 0 {
+		total, ok := c.TotalResults()
+		if !ok {
+			return errors.New("the search result page didn't show a total")
+		}
+		last, _ = ResultPosition(total, c.perPage)
+	}
+	err = c.seek(ctx, first)
+	if err != nil {
+		return err
+	}
+	for page := first; page <= last; page++ {
+		if page > first {
+			c.logger.Debug("requesting page", "page", page)
+			err = c.requestPage(ctx, page)
+			if errors.Is(err, ErrSessionExpired) {
+				c.logger.Info("session expired; starting a new one", "page", page)
+				err = c.refresh(ctx, page)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		entries, err := ParseResultGrid(c.grid, page, c.perPage)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			err = fn(e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
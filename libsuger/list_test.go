@@ -0,0 +1,84 @@
+package libsuger
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseResultGrid(t *testing.T) {
+	html, err := os.ReadFile(filepath.Join("testdata", "grid.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the grid is of page 2, the last, of 24 results
+	entries, err := ParseResultGrid(html, 2, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ListEntry{
+		{Name: "GHOST IN THE SHELL", Page: 2, Row: 0, Index: 21, Link: "Title$0"},
+		{Name: "TOM & JERRY: THE MOVIE", Page: 2, Row: 1, Index: 22, Link: "Title$1"},
+		{Name: "千与千寻 (SPIRITED AWAY)", Page: 2, Row: 2, Index: 23, Link: "Title$2"},
+		{Name: "THE OFFICE (SEASON 2)", Page: 2, Row: 3, Index: 24, Link: "Title$3"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %v entries, want %v: %+v", len(entries), len(want), entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %v: got %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	_, err = ParseResultGrid([]byte(fakeSearchForm), 1, 20)
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("search form: got error %v, want ErrSessionExpired", err)
+	}
+}
+
+func TestListTitles(t *testing.T) {
+	site, srv := newFakeSite(t, 45)
+	c, err := NewCrawler(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []ListEntry
+	err = c.ListTitles(context.Background(), 2, 0, func(e ListEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pages 2 and 3 list results 21 to 45
+	if len(entries) != 25 {
+		t.Fatalf("listed %v titles, want 25", len(entries))
+	}
+	for i, e := range entries {
+		index := 21 + i
+		if e.Index != index || e.Name != fakeName(index) {
+			t.Errorf("entry %v: got %+v, want %q, result %v", i, e, fakeName(index), index)
+		}
+	}
+	// one request per page, and none for a title page
+	want := []string{"Search", "Page$2", "Page$3"}
+	if got := site.Events(); !equalStrings(got, want) {
+		t.Errorf("postbacks %q, want %q", got, want)
+	}
+
+	stop := errors.New("stop")
+	err = c.ListTitles(context.Background(), 1, 0, func(e ListEntry) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("got error %v, want fn's", err)
+	}
+	err = c.ListTitles(context.Background(), 0, 0, nil)
+	if err == nil || !strings.Contains(err.Error(), "first page") {
+		t.Errorf("first page 0: got error %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package libsuger
+
+import (
+	"encoding/json"
+	"github.com/colinhb/suger/internal/atomicfile"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Manifest is a durable record of which results (by index, counting from 1 as for NewJob) a crawl has successfully written, so that a restarted crawl can skip them. It is kept as a sorted list of non-overlapping [start, stop) ranges and is safe for concurrent use.
+type Manifest struct {
+	Done [][2]int `json:"done"`
+	path string
+	mu   sync.Mutex
+}
+
+// LoadManifest reads the Manifest at path. If there is no file at path yet, it returns an empty Manifest that will be created there by the first call to Add.
+func LoadManifest(path string) (*Manifest, error) {
+	m := &Manifest{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Has reports whether result index is recorded in the Manifest.
+func (m *Manifest) Has(index int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := sort.Search(len(m.Done), func(i int) bool {
+		return m.Done[i][1] > index
+	})
+	return i < len(m.Done) && m.Done[i][0] <= index
+}
+
+// Add records result index in the Manifest and saves it. The file is replaced atomically, so a crash part way through leaves the previous version intact.
+func (m *Manifest) Add(index int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Done = addRange(m.Done, index)
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(m.path, data, 0644)
+}
+
+// addRange returns ranges with index added, merging neighbouring ranges.
+func addRange(ranges [][2]int, index int) [][2]int {
+	// i is the first range that ends at or after index
+	i := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i][1] >= index
+	})
+	switch {
+	case i < len(ranges) && ranges[i][0] <= index && index < ranges[i][1]:
+		// already present
+	case i < len(ranges) && ranges[i][1] == index:
+		// extends range i; it may now touch the next
+		ranges[i][1] = index + 1
+		if i+1 < len(ranges) && ranges[i+1][0] == index+1 {
+			ranges[i][1] = ranges[i+1][1]
+			ranges = append(ranges[:i+1], ranges[i+2:]...)
+		}
+	case i < len(ranges) && ranges[i][0] == index+1:
+		ranges[i][0] = index
+	default:
+		ranges = append(ranges, [2]int{})
+		copy(ranges[i+1:], ranges[i:])
+		ranges[i] = [2]int{index, index + 1}
+	}
+	return ranges
+}
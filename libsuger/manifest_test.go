@@ -0,0 +1,148 @@
+package libsuger
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// checkRanges checks that ranges are non-empty, in order, and neither overlap nor touch (touching ranges should have been merged).
+func checkRanges(t *testing.T, ranges [][2]int) {
+	t.Helper()
+	for i, r := range ranges {
+		if r[0] >= r[1] {
+			t.Errorf("%v: empty range %v", ranges, r)
+		}
+		if i > 0 && ranges[i-1][1] >= r[0] {
+			t.Errorf("%v: range %v overlaps or touches %v", ranges, r, ranges[i-1])
+		}
+	}
+}
+
+func TestAddRange(t *testing.T) {
+	tests := []struct {
+		ranges [][2]int
+		index  int
+		want   [][2]int
+	}{
+		// into an empty manifest
+		{nil, 5, [][2]int{{5, 6}}},
+		// already present, at the start, middle and end of a range
+		{[][2]int{{3, 6}}, 3, [][2]int{{3, 6}}},
+		{[][2]int{{3, 6}}, 4, [][2]int{{3, 6}}},
+		{[][2]int{{3, 6}}, 5, [][2]int{{3, 6}}},
+		// extends a range
+		{[][2]int{{3, 6}}, 6, [][2]int{{3, 7}}},
+		{[][2]int{{3, 6}, {10, 12}}, 6, [][2]int{{3, 7}, {10, 12}}},
+		// extends a range, and merges it with the next
+		{[][2]int{{3, 6}, {7, 9}}, 6, [][2]int{{3, 9}}},
+		{[][2]int{{1, 2}, {3, 6}, {7, 9}, {20, 21}}, 6, [][2]int{{1, 2}, {3, 9}, {20, 21}}},
+		// prepends to a range
+		{[][2]int{{3, 6}}, 2, [][2]int{{2, 6}}},
+		{[][2]int{{1, 2}, {4, 6}}, 3, [][2]int{{1, 2}, {3, 6}}},
+		// touches no range: before, between and after
+		{[][2]int{{3, 6}}, 1, [][2]int{{1, 2}, {3, 6}}},
+		{[][2]int{{3, 6}, {10, 12}}, 8, [][2]int{{3, 6}, {8, 9}, {10, 12}}},
+		{[][2]int{{3, 6}}, 8, [][2]int{{3, 6}, {8, 9}}},
+	}
+	for _, tt := range tests {
+		in := append([][2]int(nil), tt.ranges...)
+		got := addRange(in, tt.index)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v add %v: got %v, want %v", tt.ranges, tt.index, got, tt.want)
+		}
+		checkRanges(t, got)
+	}
+}
+
+func TestAddRangeAnyOrder(t *testing.T) {
+	// every index from 1 to 30, added in a scrambled order, ends up as one range
+	var ranges [][2]int
+	for i := 0; i < 30; i++ {
+		ranges = addRange(ranges, i*7%30+1)
+		checkRanges(t, ranges)
+	}
+	want := [][2]int{{1, 31}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestManifestHas(t *testing.T) {
+	m := &Manifest{Done: [][2]int{{3, 6}, {10, 11}}}
+	tests := []struct {
+		index int
+		want  bool
+	}{
+		{1, false},
+		{2, false},
+		{3, true},
+		{5, true},
+		{6, false}, // stop is exclusive
+		{9, false},
+		{10, true},
+		{11, false},
+		{100, false},
+	}
+	for _, tt := range tests {
+		if got := m.Has(tt.index); got != tt.want {
+			t.Errorf("%v has %v: got %v, want %v", m.Done, tt.index, got, tt.want)
+		}
+	}
+	if (&Manifest{}).Has(1) {
+		t.Errorf("an empty manifest has 1")
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("loading a missing file: %v", err)
+	}
+	if len(m.Done) != 0 {
+		t.Errorf("loading a missing file: got %v, want an empty manifest", m.Done)
+	}
+	_, err = os.Stat(path)
+	if !os.IsNotExist(err) {
+		t.Errorf("loading a missing file created it")
+	}
+	for _, index := range []int{4, 2, 3, 9, 1} {
+		err := m.Add(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	loaded, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int{{1, 5}, {9, 10}}
+	if !reflect.DeepEqual(loaded.Done, want) {
+		t.Errorf("loaded %v, want %v", loaded.Done, want)
+	}
+	// a loaded manifest saves back to the file it came from
+	err = loaded.Add(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = [][2]int{{1, 6}, {9, 10}}
+	if !reflect.DeepEqual(again.Done, want) {
+		t.Errorf("loaded %v after adding 5, want %v", again.Done, want)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	err = os.WriteFile(bad, []byte("not json"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadManifest(bad)
+	if err == nil {
+		t.Errorf("loading a corrupt file: got no error")
+	}
+}
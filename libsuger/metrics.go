@@ -0,0 +1,29 @@
+package libsuger
+
+// Metrics is told of what a crawl is doing as it happens, so that it can be exported to a monitoring system. For Prometheus, say, an implementation would increment a counter registered with the caller's prometheus.Registerer in each of Request, Error, Retry and Result, and move a gauge up and down in WorkerStarted and WorkerStopped. Its methods are called from every worker at once, so must be safe for concurrent use.
+type Metrics interface {
+	Request()       // an HTTP request was sent
+	Error()         // an attempt at a Job failed (see CrawlError)
+	Retry()         // CrawlRange started a Job again after it failed
+	Result()        // CrawlRange stored a Result (see ResultStore)
+	WorkerStarted() // CrawlRange started a worker
+	WorkerStopped() // a worker started by CrawlRange finished
+}
+
+// noMetrics is the Metrics a Crawler has by default, which ignores everything.
+type noMetrics struct{}
+
+func (noMetrics) Request()       {}
+func (noMetrics) Error()         {}
+func (noMetrics) Retry()         {}
+func (noMetrics) Result()        {}
+func (noMetrics) WorkerStarted() {}
+func (noMetrics) WorkerStopped() {}
+
+// WithMetrics sets the Metrics the Crawler (and CrawlRange) reports to. By default nothing is reported.
+func WithMetrics(m Metrics) CrawlerOption {
+	return func(c *Crawler) error {
+		c.metrics = m
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package libsuger
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// countingMetrics is a Metrics that counts what it is told, as a test registry's counters would.
+type countingMetrics struct {
+	mu                                  sync.Mutex
+	requests, errors, retries, results  int
+	started, stopped, inFlight, maxLive int
+	resultsSeen                         []int // results counted at each Request, to see the counters advance during the crawl
+}
+
+func (m *countingMetrics) Request() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = m.requests + 1
+	m.resultsSeen = append(m.resultsSeen, m.results)
+}
+
+func (m *countingMetrics) Error() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = m.errors + 1
+}
+
+func (m *countingMetrics) Retry() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = m.retries + 1
+}
+
+func (m *countingMetrics) Result() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results = m.results + 1
+}
+
+func (m *countingMetrics) WorkerStarted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = m.started + 1
+	m.inFlight = m.inFlight + 1
+	if m.inFlight > m.maxLive {
+		m.maxLive = m.inFlight
+	}
+}
+
+func (m *countingMetrics) WorkerStopped() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = m.stopped + 1
+	m.inFlight = m.inFlight - 1
+}
+
+func TestWithMetrics(t *testing.T) {
+	site, srv := newFakeSite(t, 60)
+	failed := false
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		if r.Event == "Title$5" && !failed {
+			failed = true
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	}
+	m := &countingMetrics{}
+	sum, err := CrawlRange(context.Background(), 1, 60, 3, storeResults(new([]Result)), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}), WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.requests != len(site.Requests()) || m.requests != sum.Requests {
+		t.Errorf("%v requests counted, the site got %v", m.requests, len(site.Requests()))
+	}
+	if m.errors != 1 || m.retries != 1 {
+		t.Errorf("%v errors and %v retries counted, want 1 each", m.errors, m.retries)
+	}
+	if m.results != 60 {
+		t.Errorf("%v results counted, want 60", m.results)
+	}
+	if m.started != 4 || m.stopped != m.started || m.inFlight != 0 {
+		t.Errorf("%v workers started and %v stopped, want 4 each (3 and a retry)", m.started, m.stopped)
+	}
+	if m.maxLive > 3 {
+		t.Errorf("%v workers counted in flight at once, want at most 3", m.maxLive)
+	}
+	// the counters moved as the crawl went, not all at the end
+	last := m.resultsSeen[len(m.resultsSeen)-1]
+	if last == 0 || last == 60 {
+		t.Errorf("by the last request, %v results had been counted", last)
+	}
+}
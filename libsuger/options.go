@@ -0,0 +1,252 @@
+package libsuger
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CrawlerOption configures a Crawler. Options are passed to NewCrawler and applied in order.
+type CrawlerOption func(*Crawler) error
+
+// WithRoundTripper wraps the Crawler's transport with wrap, which is given the current transport (http.DefaultTransport if none has been set) and returns the one to use. Every request made by the Crawler passes through the returned RoundTripper. Wrappers stack: a wrapper added by a later option sees requests before one added by an earlier option.
+func WithRoundTripper(wrap func(http.RoundTripper) http.RoundTripper) CrawlerOption {
+	return func(c *Crawler) error {
+		c.Transport = wrap(c.transport())
+		return nil
+	}
+}
+
+// HTTPDoer sends an HTTP request and returns its response, as http.Client does.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// WithHTTPDoer makes the Crawler send every request with d in place of its own http.Client, e.g. a fake in a test that answers each request with a canned page, with no server at all. The Crawler still adds its headers, waits out its delay and rate limit, and counts and checks each response, but options that configure its Client (WithTimeout, WithProxy, WithRoundTripper and those built on it, such as WithRecording, WithTrace and WithRequestInterceptor) and its cookie jar have no effect on d. The Crawlers of a CrawlRange all share d, so it must be safe for concurrent use. By default the Crawler's own http.Client is used.
+func WithHTTPDoer(d HTTPDoer) CrawlerOption {
+	return func(c *Crawler) error {
+		c.doer = d
+		return nil
+	}
+}
+
+// WithRequestInterceptor calls fn with every request the Crawler sends (from initialization, search, paging, and row requests alike) just before it goes out. fn may inspect or modify the request, e.g. to log it or add headers. Interceptors run inside the transport, so after the Crawler has waited out everything that holds a request back: its delay (see WithDelay), any pause of a CrawlRange's workers after the site throttled one of them (see ThrottleError), and the rate limit its workers share (see WithRate). They therefore see requests at the rate they are actually sent; a Job that is retried is seen again on every attempt. A request following a redirect goes through the transport, and so the interceptor, again. If the Crawler sends its requests with WithHTTPDoer, they don't go through its transport, and interceptors never see them.
+func WithRequestInterceptor(fn func(*http.Request)) CrawlerOption {
+	return WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+		return &interceptor{next: next, fn: fn}
+	})
+}
+
+// WithResultsPerPage sets the number of results the Crawler expects on each page of search results, from which it works out the page and row of each result. The default is ResultsPerPage.
+func WithResultsPerPage(n int) CrawlerOption {
+	return func(c *Crawler) error {
+		if n < 1 {
+			msg := fmt.Sprintf("results per page (%v) must be greater than zero.", n)
+			return errors.New(msg)
+		}
+		c.perPage = n
+		return nil
+	}
+}
+
+// WithDelay sets the minimum time between the start of one request made by the Crawler and the next, to be polite to the server. It applies to every request alike. The default is no delay.
+func WithDelay(d time.Duration) CrawlerOption {
+	return func(c *Crawler) error {
+		c.delay = d
+		return nil
+	}
+}
+
+// WithBaseURL points the Crawler at a copy of the classification database site rooted at base (e.g. an httptest.Server's URL) rather than DefaultBaseURL. All requests are made relative to it.
+func WithBaseURL(base string) CrawlerOption {
+	return func(c *Crawler) error {
+		u, err := url.Parse(base)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			msg := fmt.Sprintf("base URL %q must be absolute.", base)
+			return errors.New(msg)
+		}
+		c.start = u.ResolveReference(&url.URL{Path: searchPath}).String()
+		c.url = c.start
+		return nil
+	}
+}
+
+// WithStartURL sets the URL of the search form the Crawler starts each session from, in case the site moves it. The default is the film search page under DefaultBaseURL (or the base set by WithBaseURL). The form is posted back to wherever the site's response to it came from.
+func WithStartURL(start string) CrawlerOption {
+	return func(c *Crawler) error {
+		u, err := url.Parse(start)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			msg := fmt.Sprintf("start URL %q must be absolute.", start)
+			return errors.New(msg)
+		}
+		c.start = u.String()
+		c.url = c.start
+		return nil
+	}
+}
+
+// WithMaxAttempts sets the number of consecutive failed attempts after which CrawlRange gives up on a result (see Job.MaxAttempts). Zero, the default, means never.
+func WithMaxAttempts(n int) CrawlerOption {
+	return func(c *Crawler) error {
+		c.maxAttempts = n
+		return nil
+	}
+}
+
+// WithChunkSize makes CrawlRange split its results into Jobs of n results each, handed out to its workers as they become free, rather than one Job per worker (see Job.PartitionBySize). This sets how finely the crawl is divided separately from how many workers share it. Zero, the default, means one Job per worker.
+func WithChunkSize(n int) CrawlerOption {
+	return func(c *Crawler) error {
+		if n < 0 {
+			msg := fmt.Sprintf("chunk size (%v) must not be negative.", n)
+			return errors.New(msg)
+		}
+		c.chunkSize = n
+		return nil
+	}
+}
+
+// WithLogger sets the logger the Crawler (and CrawlRange) reports its progress to: retries and pauses at the Info and Warn levels, and each request at the Debug level. By default nothing is logged.
+func WithLogger(l *slog.Logger) CrawlerOption {
+	return func(c *Crawler) error {
+		c.logger = l
+		return nil
+	}
+}
+
+// WithSearchTerm limits the Crawler's search to titles matching term, by entering it in the search form's title field. Paging through the results works as for a full search.
+func WithSearchTerm(term string) CrawlerOption {
+	return func(c *Crawler) error {
+		c.searchTerm = term
+		return nil
+	}
+}
+
+// WithSearchTypes limits the Crawler's search to the given classification types, each one of SearchTypes (e.g. "feature"). By default every one of SearchTypes is searched. It returns an error for an unknown type, or if no types are given.
+func WithSearchTypes(types ...string) CrawlerOption {
+	return func(c *Crawler) error {
+		if len(types) == 0 {
+			return errors.New("no search types given")
+		}
+		for _, t := range types {
+			if _, ok := searchTypeFields[t]; !ok {
+				msg := fmt.Sprintf("unknown search type %q (known: %v)", t, strings.Join(SearchTypes, ", "))
+				return errors.New(msg)
+			}
+		}
+		c.searchTypes = types
+		return nil
+	}
+}
+
+// WithSkip sets a predicate the Crawler consults before fetching each row: if skip returns true for a row's search result page and row, the row is passed over without being requested. This is how a crawl resumes without re-fetching rows it already has.
+func WithSkip(skip func(page int, row int) bool) CrawlerOption {
+	return func(c *Crawler) error {
+		c.skip = skip
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit for each request the Crawler makes, including reading the response body. A request that times out fails the Job like any other error. Zero means no limit. The default is DefaultTimeout.
+func WithTimeout(d time.Duration) CrawlerOption {
+	return func(c *Crawler) error {
+		c.Timeout = d
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request the Crawler makes, in place of Go's default.
+func WithUserAgent(ua string) CrawlerOption {
+	return WithHeader("User-Agent", ua)
+}
+
+// WithHeader sets a header sent with every request the Crawler makes. It replaces any value set by an earlier option for the same key.
+func WithHeader(key string, value string) CrawlerOption {
+	return func(c *Crawler) error {
+		c.header.Set(key, value)
+		return nil
+	}
+}
+
+func (c *Crawler) transport() http.RoundTripper {
+	if c.Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.Transport
+}
+
+// baseTransport returns a copy of the Crawler's http.Transport (of http.DefaultTransport if it has none) and makes it the Crawler's transport, for an option (named option, for errors) to configure. It returns an error if the transport has already been wrapped (see WithRoundTripper), so such options must come before any that wrap it.
+func (c *Crawler) baseTransport(option string) (*http.Transport, error) {
+	t, ok := c.transport().(*http.Transport)
+	if !ok {
+		msg := fmt.Sprintf("%s must come before options that wrap the transport (e.g. WithRecording).", option)
+		return nil, errors.New(msg)
+	}
+	t = t.Clone()
+	c.Transport = t
+	return t, nil
+}
+
+// WithProxy sends every request the Crawler makes through the proxy at proxyURL, which must be an http, https or socks5 URL (e.g. "socks5://localhost:1080"), with any credentials as its user info. It must come before any option that wraps the transport, such as WithRecording.
+func WithProxy(proxyURL string) CrawlerOption {
+	return func(c *Crawler) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return err
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			msg := fmt.Sprintf("proxy URL %q must be http, https or socks5.", proxyURL)
+			return errors.New(msg)
+		}
+		if u.Host == "" {
+			msg := fmt.Sprintf("proxy URL %q has no host.", proxyURL)
+			return errors.New(msg)
+		}
+		t, err := c.baseTransport("WithProxy")
+		if err != nil {
+			return err
+		}
+		t.Proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// WithInsecureTLS, if insecure is true, makes the Crawler accept any TLS certificate the server presents, without verifying it. It is for debugging only, e.g. against a staging server or a local intercepting proxy with a self-signed certificate: it leaves the crawl open to interception. It must come before any option that wraps the transport, such as WithRecording. By default certificates are verified.
+func WithInsecureTLS(insecure bool) CrawlerOption {
+	return func(c *Crawler) error {
+		t, err := c.baseTransport("WithInsecureTLS")
+		if err != nil {
+			return err
+		}
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.InsecureSkipVerify = insecure
+		return nil
+	}
+}
+
+// interceptor is a RoundTripper that hands each request to fn before passing it on.
+type interceptor struct {
+	next http.RoundTripper
+	fn   func(*http.Request)
+}
+
+func (t *interceptor) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	t.fn(req)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,66 @@
+package libsuger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Progress is how far a CrawlRange has got, as passed to the function set by WithProgress.
+type Progress struct {
+	Done     int           // results finished with: crawled, missing or given up on. Rows passed over by the Crawler's skip predicate (see WithSkip) don't count
+	Total    int           // results in the range
+	Requests int           // HTTP requests made so far
+	Elapsed  time.Duration // wall time since the crawl began
+}
+
+// Fraction returns the fraction of the range that is done, from 0 to 1.
+func (p Progress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Done) / float64(p.Total)
+}
+
+// RequestsPerSecond returns the average rate of requests so far.
+func (p Progress) RequestsPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Requests) / p.Elapsed.Seconds()
+}
+
+// Remaining estimates the time left to finish the range, assuming the rest goes at the average rate so far. It is zero if nothing is done yet.
+func (p Progress) Remaining() time.Duration {
+	if p.Done <= 0 || p.Done >= p.Total {
+		return 0
+	}
+	perResult := p.Elapsed / time.Duration(p.Done)
+	return perResult * time.Duration(p.Total-p.Done)
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("crawled %v/%v (%.0f%%) at %.1f req/s, ETA %v", p.Done, p.Total, 100*p.Fraction(), p.RequestsPerSecond(), p.Remaining().Round(time.Second))
+}
+
+// progressConfig is the reporting set by WithProgress.
+type progressConfig struct {
+	every    int           // results between reports
+	interval time.Duration // time between reports
+	fn       func(Progress)
+}
+
+// WithProgress makes CrawlRange call fn with its Progress every time another every results are done, and every interval, whichever comes first, and once more when it finishes. Zero turns off either trigger. fn is only ever called from the goroutine that called CrawlRange, with Done never decreasing, so it may drive a progress bar directly. It returns an error if both every and interval are zero, or either is negative.
+func WithProgress(every int, interval time.Duration, fn func(Progress)) CrawlerOption {
+	return func(c *Crawler) error {
+		if every < 0 || interval < 0 {
+			msg := fmt.Sprintf("progress every %v results or %v must not be negative.", every, interval)
+			return errors.New(msg)
+		}
+		if every == 0 && interval == 0 {
+			return errors.New("progress needs a number of results or an interval to report at.")
+		}
+		c.progress = &progressConfig{every: every, interval: interval, fn: fn}
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package libsuger
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// checkMonotonic checks that the counts of each Progress in reports never decrease, and that the last has every result done.
+func checkMonotonic(t *testing.T, reports []Progress, total int) {
+	t.Helper()
+	if len(reports) == 0 {
+		t.Fatal("no progress reported")
+	}
+	for i := 1; i < len(reports); i++ {
+		a, b := reports[i-1], reports[i]
+		if b.Done < a.Done || b.Requests < a.Requests || b.Elapsed < a.Elapsed {
+			t.Errorf("report %v went backwards: %+v after %+v", i, b, a)
+		}
+	}
+	for i, p := range reports {
+		if p.Total != total || p.Done > total {
+			t.Errorf("report %v: %+v, want a total of %v", i, p, total)
+		}
+	}
+	if last := reports[len(reports)-1]; last.Done != total {
+		t.Errorf("last report %+v, want all %v done", last, total)
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	// the last two results are missing rows, which count as done
+	_, srv := newFakeSite(t, 43)
+	var reports []Progress
+	var results []Result
+	_, err := CrawlRange(context.Background(), 1, 45, 3, storeResults(&results), WithBaseURL(srv.URL),
+		WithProgress(5, 0, func(p Progress) { reports = append(reports, p) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMonotonic(t, reports, 45)
+	// one every 5 results, and the last when finished
+	if len(reports) < 45/5 {
+		t.Errorf("reported %v times, want at least %v", len(reports), 45/5)
+	}
+	for i := 1; i < len(reports)-1; i++ {
+		if reports[i].Done-reports[i-1].Done < 5 {
+			t.Errorf("report %v came %v results after the last, want 5", i, reports[i].Done-reports[i-1].Done)
+		}
+	}
+}
+
+func TestWithProgressInterval(t *testing.T) {
+	_, srv := newFakeSite(t, 20)
+	var reports []Progress
+	var results []Result
+	_, err := CrawlRange(context.Background(), 1, 20, 2, storeResults(&results), WithBaseURL(srv.URL), WithDelay(2*time.Millisecond),
+		WithProgress(0, time.Millisecond, func(p Progress) { reports = append(reports, p) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMonotonic(t, reports, 20)
+	if len(reports) < 2 {
+		t.Errorf("reported %v times, want some before the end", len(reports))
+	}
+}
+
+func TestWithProgressInvalid(t *testing.T) {
+	fn := func(Progress) {}
+	for _, tt := range []struct {
+		every    int
+		interval time.Duration
+	}{{0, 0}, {-1, 0}, {0, -time.Second}} {
+		_, err := NewCrawler(WithProgress(tt.every, tt.interval, fn))
+		if err == nil {
+			t.Errorf("WithProgress(%v, %v) succeeded", tt.every, tt.interval)
+		}
+	}
+}
+
+func TestProgressEstimates(t *testing.T) {
+	p := Progress{Done: 25, Total: 100, Requests: 50, Elapsed: 10 * time.Second}
+	if p.Fraction() != 0.25 || p.RequestsPerSecond() != 5 || p.Remaining() != 30*time.Second {
+		t.Errorf("%+v: Fraction %v, RequestsPerSecond %v, Remaining %v, want 0.25, 5, 30s", p, p.Fraction(), p.RequestsPerSecond(), p.Remaining())
+	}
+	if s := p.String(); s != "crawled 25/100 (25%) at 5.0 req/s, ETA 30s" {
+		t.Errorf("String() = %q", s)
+	}
+	for _, p := range []Progress{{}, {Total: 100}, {Done: 100, Total: 100, Elapsed: time.Minute}} {
+		if p.Remaining() != 0 {
+			t.Errorf("%+v: Remaining() = %v, want 0", p, p.Remaining())
+		}
+	}
+	if (Progress{Done: 1}).Fraction() != 0 {
+		t.Error("Fraction() of no total isn't 0")
+	}
+}
@@ -0,0 +1,45 @@
+package libsuger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// limiter spaces out requests to at most a given rate. It is shared by every Crawler of a CrawlRange, so the rate bounds the crawl as a whole however many workers there are.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration // between requests
+	next     time.Time     // when the next request may be sent
+}
+
+func newLimiter(rate float64) *limiter {
+	return &limiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait waits for the limiter's next free slot, and takes it. It returns early with ctx.Err() if ctx is done first, in which case the slot is still used.
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	t := l.next
+	if t.Before(now) {
+		t = now
+	}
+	l.next = t.Add(l.interval)
+	l.mu.Unlock()
+	return sleep(ctx, t.Sub(now))
+}
+
+// WithRate limits the Crawler to rate requests per second. The limit is shared by all the Crawlers of a CrawlRange, so it caps the rate of the whole crawl, unlike WithDelay. It returns an error if rate isn't positive.
+func WithRate(rate float64) CrawlerOption {
+	return func(c *Crawler) error {
+		if rate <= 0 {
+			msg := fmt.Sprintf("rate (%v) must be greater than zero", rate)
+			return errors.New(msg)
+		}
+		c.limiter = newLimiter(rate)
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package libsuger
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithRateBoundsAllWorkers(t *testing.T) {
+	const rate = 50 // requests per second
+	site, srv := newFakeSite(t, 40)
+	var mu sync.Mutex
+	var times []time.Time
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		return false
+	}
+	var results []Result
+	sum, err := CrawlRange(context.Background(), 1, 40, 8, storeResults(&results), WithBaseURL(srv.URL), WithRate(rate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Results != 40 {
+		t.Errorf("got %v results, want 40", sum.Results)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// eight workers unlimited would send them all at once
+	span := times[len(times)-1].Sub(times[0])
+	min := time.Duration(len(times)-1) * time.Second / rate
+	if span < min*9/10 {
+		t.Errorf("%v requests took %v, want at least %v at %v a second", len(times), span, min, rate)
+	}
+	// no window of a fifth of a second holds more than its share
+	window := time.Second / 5
+	for i := range times {
+		n := 0
+		for j := i; j < len(times) && times[j].Sub(times[i]) < window; j++ {
+			n = n + 1
+		}
+		if n > rate/5+1 {
+			t.Errorf("%v requests within %v of request %v, want at most %v", n, window, i, rate/5+1)
+			break
+		}
+	}
+	for _, r := range []float64{0, -1} {
+		if _, err := NewCrawler(WithRate(r)); err == nil {
+			t.Errorf("WithRate(%v) succeeded", r)
+		}
+	}
+}
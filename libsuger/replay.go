@@ -0,0 +1,157 @@
+package libsuger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/colinhb/suger/internal/atomicfile"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+)
+
+// WithRecording saves every request/response pair the Crawler makes to dir, so that the session can later be served by WithReplay. Responses are stored one per file, named by a hash of the request signature (method, URL, and body).
+func WithRecording(dir string) CrawlerOption {
+	return WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+		return &recorder{dir: dir, next: next}
+	})
+}
+
+// WithReplay serves every request from responses previously saved to dir by WithRecording, without touching the network. A request that was not recorded fails with an error.
+func WithReplay(dir string) CrawlerOption {
+	return WithRoundTripper(func(http.RoundTripper) http.RoundTripper {
+		return &replayer{dir: dir}
+	})
+}
+
+// WithCache keeps an on-disk cache of the Crawler's responses in dir, which is created if need be. A request whose response is in the cache (as recorded by WithRecording, under the same key) is served from it without touching the network; any other is sent, and its response cached if it is a 200 OK or a redirect (see cacheable), as the site answers some postbacks with a redirect to the page they lead to. It is meant for development, to avoid re-fetching the same pages while working on the scraper.
+func WithCache(dir string) CrawlerOption {
+	return func(c *Crawler) error {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+		return WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+			return &cache{dir: dir, next: next}
+		})(c)
+	}
+}
+
+// requestKey returns the signature under which a request's response is recorded. It reads and restores the request body.
+func requestKey(req *http.Request) (string, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		body = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.String())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type recorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// DumpResponse reads the body and replaces it, so resp is still usable
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	path := filepath.Join(t.dir, key+".http")
+	err = atomicfile.WriteFile(path, dump, 0644)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+type replayer struct {
+	dir string
+}
+
+func (t *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(t.dir, key+".http")
+	dump, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		msg := fmt.Sprintf("no recorded response for %s %s", req.Method, req.URL)
+		return nil, errors.New(msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+}
+
+type cache struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(t.dir, key+".http")
+	dump, err := ioutil.ReadFile(path)
+	if err == nil {
+		return http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || !cacheable(resp.StatusCode) {
+		return resp, err
+	}
+	dump, err = httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	err = atomicfile.WriteFile(path, dump, 0644)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// cacheable returns true if a response with the given status is kept by WithCache: a 200 OK, or a redirect (other than 304 Not Modified, which has nothing to keep). The request a redirect leads to is cached in its own right.
+func cacheable(status int) bool {
+	if status == http.StatusOK {
+		return true
+	}
+	return status >= 300 && status < 400 && status != http.StatusNotModified
+}
@@ -0,0 +1,170 @@
+package libsuger
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "record the fixtures in testdata again, from a fake site")
+
+// replayBase is the site the fixture in testdata/replay was recorded from.
+const replayBase = "http://suger.test"
+
+// replayDir holds a crawl of results 19 to 22 of a fakeSite with 25 results, recorded by WithRecording: a multi-page crawl, as they span pages 1 and 2.
+var replayDir = filepath.Join("testdata", "replay")
+
+func TestReplayCrawl(t *testing.T) {
+	if *update {
+		os.RemoveAll(replayDir)
+		err := os.MkdirAll(replayDir, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		site := siteTransport{&fakeSite{Total: 25}}
+		record := WithRoundTripper(func(http.RoundTripper) http.RoundTripper { return site })
+		_, err = CrawlRange(context.Background(), 19, 4, 1, ResultStoreFunc(func(Result) error { return nil }),
+			WithBaseURL(replayBase), record, WithRecording(replayDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var results []Result
+	_, err := CrawlRange(context.Background(), 19, 4, 1, storeResults(&results),
+		WithBaseURL(replayBase), WithReplay(replayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %v results, want 4", len(results))
+	}
+	for i, r := range results {
+		index := 19 + i
+		if r.Index != index {
+			t.Errorf("result %v has Index %v, want %v", i, r.Index, index)
+		}
+		title, err := NewTitleFromHTML(r.HTML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title.Name != fakeName(index) || title.ID != fakeID(index) {
+			t.Errorf("result %v is %q (%v), want %q (%v)", index, title.Name, title.ID, fakeName(index), fakeID(index))
+		}
+	}
+}
+
+func TestReplayUnrecorded(t *testing.T) {
+	c, err := NewCrawler(WithBaseURL(replayBase), WithReplay(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.doInit(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("got error %v, want one for a request not recorded", err)
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	site, srv := newFakeSite(t, 3)
+	c, err := NewCrawler(WithBaseURL(srv.URL), WithRecording(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := c.CrawlResults(context.Background(), Job{start: 1, stop: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(site.Requests())
+
+	c, err = NewCrawler(WithBaseURL(srv.URL), WithReplay(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.CrawlResults(context.Background(), Job{start: 1, stop: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(site.Requests()) != n {
+		t.Errorf("replay made %v requests to the site", len(site.Requests())-n)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("replayed %v results, recorded %v", len(got), len(want))
+	}
+	for i := range got {
+		if string(got[i].HTML) != string(want[i].HTML) {
+			t.Errorf("result %v replayed differently from how it was recorded", i)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		key := r.Method + " " + r.URL.Path + " " + string(body)
+		mu.Lock()
+		hits[key] = hits[key] + 1
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/page":
+			fmt.Fprint(w, "page")
+		case "/moved":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c, err := NewCrawler(WithCache(filepath.Join(t.TempDir(), "cache")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := func() {
+		ctx := context.Background()
+		for _, path := range []string{"/page", "/moved", "/missing"} {
+			resp, err := c.get(ctx, srv.URL+path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+		for _, q := range []string{"a", "b"} {
+			resp, err := c.postForm(ctx, srv.URL+"/page", url.Values{"q": {q}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	}
+	requests()
+	requests()
+	want := map[string]int{
+		"GET /page ":     1, // also reached by the redirect, the first time
+		"GET /moved ":    1,
+		"GET /missing ":  2, // not cached
+		"POST /page q=a": 1,
+		"POST /page q=b": 1,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for req, n := range want {
+		if hits[req] != n {
+			t.Errorf("%q reached the server %v times, want %v", req, hits[req], n)
+		}
+	}
+	if len(hits) != len(want) {
+		t.Errorf("server got requests %v", hits)
+	}
+}
@@ -0,0 +1,321 @@
+package libsuger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileError records a failure to read or scrape a single file.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// ScrapeErrors is the error returned by ScrapeDir when some files could not be scraped. It lists every failure.
+type ScrapeErrors []*FileError
+
+func (errs ScrapeErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	return fmt.Sprintf("%v files could not be scraped (first: %v)", len(errs), errs[0])
+}
+
+// Scraper scrapes directories of title pages. The zero value scrapes one file at a time.
+type Scraper struct {
+	Workers   int          // number of files to read and parse concurrently; less than 1 means 1
+	Glob      string       // pattern (as for filepath.Match) a file's name must match to be scraped; empty means *.html or *.htm, optionally gzipped (.gz)
+	Recursive bool         // also scrape files in subdirectories (except hidden ones) of the directory
+	Logger    *slog.Logger // if not nil, files passed over are logged to it at the Debug level
+	Limit     int          // if greater than 0, stop after this many Titles have been scraped, the first in order (files that can't be scraped don't count)
+	Strict    bool         // also reject files that aren't title pages as Crawl would (see CheckPage), or that are scraped with Warnings
+}
+
+// ScrapeDir reads every HTML file in htmlDir as a title page (see NewTitleFromHTML) and returns the Titles. Files that can't be read or scraped are skipped; if there are any, the Titles that could be scraped are returned together with a ScrapeErrors listing the failures. It uses the zero Scraper.
+func ScrapeDir(htmlDir string) ([]*Title, error) {
+	return (&Scraper{}).ScrapeDir(htmlDir)
+}
+
+// ScrapeDirFunc is like ScrapeDir, but instead of collecting the Titles it calls fn with each one as it is scraped; see Scraper.ScrapeDirFunc.
+func ScrapeDirFunc(htmlDir string, fn func(*Title) error, errFn func(*FileError) error) error {
+	return (&Scraper{}).ScrapeDirFunc(htmlDir, fn, errFn)
+}
+
+// ScrapeDir is like the package-level ScrapeDir, but uses s's settings.
+func (s *Scraper) ScrapeDir(htmlDir string) ([]*Title, error) {
+	var titles []*Title
+	var errs ScrapeErrors
+	collect := func(t *Title) error {
+		titles = append(titles, t)
+		return nil
+	}
+	skip := func(e *FileError) error {
+		errs = append(errs, e)
+		return nil
+	}
+	err := s.ScrapeDirFunc(htmlDir, collect, skip)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return titles, errs
+	}
+	return titles, nil
+}
+
+// ScrapeDirFunc reads every file in htmlDir that s wants (see Scraper.Wants) as a title page and calls fn with each Title as it is scraped, so that the caller need not hold them all in memory. A file that can't be read or scraped is passed to errFn as a *FileError; scraping continues if errFn returns nil. If errFn is nil, scraping stops at the first such file. ScrapeDirFunc returns the first error returned by fn or errFn.
+//
+// Up to s.Workers files are parsed at once, but fn and errFn are called from a single goroutine, in directory order.
+func (s *Scraper) ScrapeDirFunc(htmlDir string, fn func(*Title) error, errFn func(*FileError) error) error {
+	files, err := s.listFiles(htmlDir)
+	if err != nil {
+		return err
+	}
+	next := func() (entry, error) {
+		if len(files) == 0 {
+			return entry{}, io.EOF
+		}
+		path := files[0]
+		files = files[1:]
+		load := func() ([]byte, error) {
+			return ioutil.ReadFile(path)
+		}
+		return entry{path: path, load: load}, nil
+	}
+	return s.scrapeEntries(next, fn, errFn)
+}
+
+// entry is a file to be scraped: its path, and a function returning its contents.
+type entry struct {
+	path string
+	load func() ([]byte, error)
+}
+
+// scrapeEntries scrapes the entries returned by next, until it returns io.EOF, as described for ScrapeDirFunc. An entry whose path ends in .gz is decompressed. Any other error from next stops scraping and is returned.
+func (s *Scraper) scrapeEntries(next func() (entry, error), fn func(*Title) error, errFn func(*FileError) error) error {
+	if errFn == nil {
+		errFn = func(e *FileError) error {
+			return e
+		}
+	}
+	workers := s.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Each entry gets a channel its outcome is sent on. The channels are
+	// queued in order, and the queue's buffer bounds how many entries are
+	// in flight.
+	type outcome struct {
+		title *Title
+		err   *FileError
+		fatal error // from next
+	}
+	queue := make(chan chan outcome, workers-1)
+	stop := make(chan struct{})
+	// On return, stop the producer and wait for it and every worker, so
+	// that none is still in next or reading an entry when the caller
+	// closes what they read from (e.g. an archive).
+	var wg sync.WaitGroup
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(queue)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			e, err := next()
+			if err == io.EOF {
+				return
+			}
+			c := make(chan outcome, 1)
+			select {
+			case queue <- c:
+			case <-stop:
+				return
+			}
+			if err != nil {
+				c <- outcome{fatal: err}
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				title, err := s.scrapeEntry(e)
+				if err != nil {
+					c <- outcome{err: &FileError{Path: e.path, Err: err}}
+					return
+				}
+				c <- outcome{title: title}
+			}()
+		}
+	}()
+
+	scraped := 0
+	for c := range queue {
+		o := <-c
+		var err error
+		switch {
+		case o.fatal != nil:
+			err = o.fatal
+		case o.err != nil:
+			err = errFn(o.err)
+		default:
+			err = fn(o.title)
+			scraped = scraped + 1
+		}
+		if err != nil {
+			return err
+		}
+		if s.Limit > 0 && scraped >= s.Limit {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Wants returns true if the file described by fi should be scraped: it isn't a directory or a hidden (dot) file, and its name matches s.Glob. Files whose names end in .gz are decompressed when scraped.
+func (s *Scraper) Wants(fi os.FileInfo) bool {
+	name := fi.Name()
+	if fi.IsDir() || strings.HasPrefix(name, ".") {
+		return false
+	}
+	if s.Glob != "" {
+		ok, _ := filepath.Match(s.Glob, name)
+		return ok
+	}
+	ext := strings.ToLower(filepath.Ext(strings.TrimSuffix(name, ".gz")))
+	return ext == ".html" || ext == ".htm"
+}
+
+// listFiles returns the paths of the files in htmlDir (and, if s.Recursive, its subdirectories) that s wants, in lexical order.
+func (s *Scraper) listFiles(htmlDir string) ([]string, error) {
+	if s.Recursive {
+		return s.walkFiles(htmlDir)
+	}
+	infos, err := ioutil.ReadDir(htmlDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, fi := range infos {
+		path := filepath.Join(htmlDir, fi.Name())
+		if s.Wants(fi) {
+			files = append(files, path)
+		} else {
+			s.logSkip(path)
+		}
+	}
+	return files, nil
+}
+
+// walkFiles is listFiles for a Recursive Scraper.
+func (s *Scraper) walkFiles(htmlDir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(htmlDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != htmlDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if s.Wants(fi) {
+			files = append(files, path)
+		} else {
+			s.logSkip(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// scrapeEntry loads e, decompressing it if its path ends in .gz, and scrapes it, checking it first if s is Strict.
+func (s *Scraper) scrapeEntry(e entry) (*Title, error) {
+	html, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(e.path, ".gz") {
+		html, err = gunzip(html)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.Strict {
+		err = CheckPage(html)
+		if err != nil {
+			return nil, err
+		}
+	}
+	title, err := NewTitleFromHTML(html)
+	if err != nil {
+		return nil, err
+	}
+	if s.Strict && len(title.Warnings) > 0 {
+		return nil, &ParseError{errors.New(strings.Join(title.Warnings, "; "))}
+	}
+	return title, nil
+}
+
+// ErrEmptyPage is the error returned by CheckPage for an empty file.
+var ErrEmptyPage = errors.New("empty page")
+
+// CheckPage returns an error unless html is a title page, as Crawl checks each row it fetches: ErrEmptyPage if it is empty, ErrNoSuchRow if it is the search result page, ErrSessionExpired if it is a new search form, ErrBlockedPage if it isn't one of the site's forms at all (as for a page blocking the crawl, or a proxy's error page), and otherwise a ParseError. Saved by a crawl, any of these mean the crawl went wrong.
+func CheckPage(html []byte) error {
+	if len(bytes.TrimSpace(html)) == 0 {
+		return ErrEmptyPage
+	}
+	return checkResponse(html)
+}
+
+// logSkip logs that the file at path is passed over, if s has a Logger.
+func (s *Scraper) logSkip(path string) {
+	if s.Logger != nil {
+		s.Logger.Debug("skipping file", "path", path)
+	}
+}
+
+// gunzip returns the decompressed contents of gzipped data.
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
@@ -0,0 +1,258 @@
+package libsuger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFixtureCopies writes n copies of the title page testdata/name to dir, as title-1-0.html, title-1-1.html, and so on.
+func writeFixtureCopies(tb testing.TB, dir string, name string, n int) {
+	tb.Helper()
+	html, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("title-1-%v.html", i)), html, 0644)
+		if err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestScrapeDirWorkersKeepOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		// zero-padded, so directory order is numeric order
+		files[fmt.Sprintf("title-%03d.html", i)] = titlePage(fakeID(i), fakeName(i), Rating{Rating: "Parental Guidance", Decision: "Passed Clean"})
+	}
+	writeFiles(t, dir, files)
+	for _, workers := range []int{0, 1, 4, 16} {
+		s := &Scraper{Workers: workers}
+		titles, err := s.ScrapeDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(titles) != 50 {
+			t.Fatalf("%v workers: scraped %v titles, want 50", workers, len(titles))
+		}
+		for i, title := range titles {
+			if title.Name != fakeName(i) {
+				t.Errorf("%v workers: title %v is %q, want %q", workers, i, title.Name, fakeName(i))
+				break
+			}
+		}
+	}
+}
+
+// names returns the Names of titles, in order.
+func names(titles []*Title) []string {
+	var sl []string
+	for _, t := range titles {
+		sl = append(sl, t.Name)
+	}
+	return sl
+}
+
+func TestScrapeDirMixed(t *testing.T) {
+	dir := t.TempDir()
+	passed := Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}
+	writeFiles(t, dir, map[string]string{
+		"a.html":           titlePage("A", "A", passed),
+		"b.HTM":            titlePage("B", "B", passed),
+		"c.html.bak":       titlePage("C", "C", passed),
+		"d.page":           titlePage("D", "D", passed),
+		".hidden.html":     titlePage("E", "E", passed),
+		"out.json":         `[{"Name": "F"}]`,
+		"manifest.txt":     "not a page",
+		"sub.html/g.html":  titlePage("G", "G", passed),
+		"sub/h.html":       titlePage("H", "H", passed),
+		"notes/README.htm": "not a page",
+	})
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	titles, err := (&Scraper{Logger: logger}).ScrapeDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"A", "B"}
+	if got := names(titles); !equalStrings(got, want) {
+		t.Errorf("scraped %q, want %q", got, want)
+	}
+	for _, skipped := range []string{"c.html.bak", "d.page", ".hidden.html", "out.json", "manifest.txt", "sub.html"} {
+		if !strings.Contains(logged.String(), skipped) {
+			t.Errorf("skipping %v wasn't logged", skipped)
+		}
+	}
+
+	// a pattern replaces the default
+	titles, err = (&Scraper{Glob: "*.page"}).ScrapeDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"D"}
+	if got := names(titles); !equalStrings(got, want) {
+		t.Errorf("with a pattern, scraped %q, want %q", got, want)
+	}
+}
+
+func TestScrapeDirRecursive(t *testing.T) {
+	dir := t.TempDir()
+	passed := Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}
+	writeFiles(t, dir, map[string]string{
+		"top.html":                   titlePage("1", "TOP", passed),
+		"page-1/title-1-0.html":      titlePage("2", "PAGE 1 ROW 0", passed),
+		"page-1/title-1-1.html":      titlePage("3", "PAGE 1 ROW 1", passed),
+		"page-2/title-2-0.html":      titlePage("4", "PAGE 2 ROW 0", passed),
+		"page-2/deeper/title.htm":    titlePage("5", "DEEPER", passed),
+		"page-2/out.json":            "[]",
+		".cache/title-9-9.html":      titlePage("6", "HIDDEN", passed),
+		"page-3/.hidden/title.html":  titlePage("7", "HIDDEN TOO", passed),
+		"page-3/empty-dir/notes.txt": "not a page",
+	})
+	titles, err := (&Scraper{Recursive: true}).ScrapeDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"PAGE 1 ROW 0", "PAGE 1 ROW 1", "DEEPER", "PAGE 2 ROW 0", "TOP"}
+	if got := names(titles); !equalStrings(got, want) {
+		t.Errorf("scraped %q, want %q", got, want)
+	}
+	titles, err = (&Scraper{}).ScrapeDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(titles); !equalStrings(got, []string{"TOP"}) {
+		t.Errorf("without Recursive, scraped %q, want only the top level", got)
+	}
+}
+
+// BenchmarkScrapeDir scrapes a directory of copies of a saved title page with 1, 2, 4 and 8 workers, to show the speedup of scraping concurrently (given as many CPUs).
+func BenchmarkScrapeDir(b *testing.B) {
+	dir := b.TempDir()
+	writeFixtureCopies(b, dir, "feature.html", 200)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%v", workers), func(b *testing.B) {
+			s := &Scraper{Workers: workers}
+			for i := 0; i < b.N; i++ {
+				_, err := s.ScrapeDir(dir)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestScraperLimit(t *testing.T) {
+	dir := t.TempDir()
+	files := make(map[string]string)
+	for i := 0; i < 30; i++ {
+		files[fmt.Sprintf("title-%03d.html", i)] = titlePage(fakeID(i), fakeName(i), Rating{Rating: "Parental Guidance", Decision: "Passed Clean"})
+	}
+	// files that can't be scraped don't count towards the limit
+	files["title-001.html"] = "<html><body>blocked</body></html>"
+	files["title-003.html"] = ""
+	writeFiles(t, dir, files)
+	want := []string{fakeName(0), fakeName(2), fakeName(4), fakeName(5), fakeName(6)}
+	for _, workers := range []int{1, 4, 16} {
+		s := &Scraper{Workers: workers, Limit: 5}
+		var titles []*Title
+		skipped := 0
+		err := s.ScrapeDirFunc(dir, func(title *Title) error {
+			titles = append(titles, title)
+			return nil
+		}, func(e *FileError) error {
+			skipped = skipped + 1
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(titles); !equalStrings(got, want) {
+			t.Errorf("%v workers: scraped %q, want %q", workers, got, want)
+		}
+		if skipped != 2 {
+			t.Errorf("%v workers: skipped %v files, want 2", workers, skipped)
+		}
+	}
+	// a limit beyond the titles there are is no limit
+	s := &Scraper{Limit: 100}
+	titles, _ := s.ScrapeDir(dir)
+	if len(titles) != 28 {
+		t.Errorf("limit 100: scraped %v titles, want 28", len(titles))
+	}
+}
+
+func TestCheckPage(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		want  error // nil, or the sentinel error wanted
+		parse bool  // want a ParseError
+	}{
+		{"title page", fakeTitlePage(1), nil, false},
+		{"empty", "", ErrEmptyPage, false},
+		{"blank", " \n\t", ErrEmptyPage, false},
+		{"search results", (&fakeSite{Total: 3}).resultPage(1), ErrNoSuchRow, false},
+		{"search form", fakeSearchForm, ErrSessionExpired, false},
+		{"block page", "<html><body><h1>Access Denied</h1></body></html>", ErrBlockedPage, false},
+		{"other form", `<html><body><form method="post" action="./" id="form1"><span id="lblError">Error</span></form></body></html>`, nil, true},
+	}
+	for _, tt := range tests {
+		err := CheckPage([]byte(tt.html))
+		var pe *ParseError
+		switch {
+		case tt.parse:
+			if !errors.As(err, &pe) {
+				t.Errorf("%s: got error %v, want a ParseError", tt.name, err)
+			}
+		case tt.want == nil:
+			if err != nil {
+				t.Errorf("%s: got error %v", tt.name, err)
+			}
+		case !errors.Is(err, tt.want):
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestScraperStrict(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"title-1-0.html": fakeTitlePage(1),
+		"title-1-1.html": titlePage("ID2", "NO RATINGS"), // scrapes, with a warning
+		"title-1-2.html": (&fakeSite{Total: 3}).resultPage(1),
+		"title-1-3.html": "",
+	})
+	for _, strict := range []bool{false, true} {
+		s := &Scraper{Strict: strict}
+		var scraped []*Title
+		var failed []string
+		err := s.ScrapeDirFunc(dir, func(title *Title) error {
+			scraped = append(scraped, title)
+			return nil
+		}, func(e *FileError) error {
+			failed = append(failed, filepath.Base(e.Path))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// only an empty file can't be scraped at all
+		want := []string{"title-1-3.html"}
+		if strict {
+			want = []string{"title-1-1.html", "title-1-2.html", "title-1-3.html"}
+		}
+		if !equalStrings(failed, want) || len(scraped) != 4-len(want) {
+			t.Errorf("strict %v: failed %q, scraped %q, want %q failed", strict, failed, names(scraped), want)
+		}
+	}
+}
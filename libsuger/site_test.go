@@ -0,0 +1,248 @@
+package libsuger
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSite is an http.Handler serving a small copy of the classification database site: the search form, pages of search results, and a title page for each result. Every page carries the hidden ASP.NET fields that must be posted back; the __VIEWSTATE records the search result page the session is on, so a postback is answered for the page it was posted from, as the real site does.
+type fakeSite struct {
+	Total   int // results found by every search
+	PerPage int // results per page; zero means ResultsPerPage
+	// Hook, if not nil, is called with each request before it is answered, and its number (counting from 1); if it returns true, it has answered the request itself.
+	Hook func(w http.ResponseWriter, r fakeRequest, n int) bool
+
+	mu       sync.Mutex
+	requests []fakeRequest
+}
+
+// fakeRequest is a request made to a fakeSite.
+type fakeRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Form   url.Values // the form posted, or nil for a GET
+	Event  string     // the postback's __EVENTARGUMENT (e.g. "Page$2"), "Search" for the search, or "" for a GET
+	Page   int        // the search result page the postback came from, or zero
+}
+
+// newFakeSite returns a fakeSite with total results, and an httptest.Server serving it that is closed when the test ends.
+func newFakeSite(t *testing.T, total int) (*fakeSite, *httptest.Server) {
+	site := &fakeSite{Total: total}
+	srv := httptest.NewServer(site)
+	t.Cleanup(srv.Close)
+	return site, srv
+}
+
+// Requests returns the requests made to the site so far, in order.
+func (s *fakeSite) Requests() []fakeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeRequest(nil), s.requests...)
+}
+
+// Events returns the Event of each postback made to the site so far, in order.
+func (s *fakeSite) Events() []string {
+	var events []string
+	for _, r := range s.Requests() {
+		if r.Method == "POST" {
+			events = append(events, r.Event)
+		}
+	}
+	return events
+}
+
+func (s *fakeSite) perPage() int {
+	if s.PerPage == 0 {
+		return ResultsPerPage
+	}
+	return s.PerPage
+}
+
+func (s *fakeSite) lastPage() int {
+	last, _ := ResultPosition(s.Total, s.perPage())
+	return last
+}
+
+func (s *fakeSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := fakeRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header}
+	if r.Method == "POST" {
+		r.ParseForm()
+		req.Form = r.PostForm
+		req.Event = r.PostForm.Get("__EVENTARGUMENT")
+		if r.PostForm.Get("btnSearch") != "" {
+			req.Event = "Search"
+		}
+		req.Page, _ = strconv.Atoi(strings.TrimPrefix(r.PostForm.Get("__VIEWSTATE"), "page-"))
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	n := len(s.requests)
+	s.mu.Unlock()
+	if s.Hook != nil && s.Hook(w, req, n) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	switch {
+	case r.Method == "GET":
+		fmt.Fprint(w, fakeSearchForm)
+	case req.Event == "Search":
+		fmt.Fprint(w, s.resultPage(1))
+	case req.Page == 0:
+		// a postback from no session the site knows of
+		fmt.Fprint(w, fakeSearchForm)
+	case strings.HasPrefix(req.Event, "Page$"):
+		page, _ := strconv.Atoi(strings.TrimPrefix(req.Event, "Page$"))
+		if !s.pagerLinks(req.Page)[page] {
+			msg := fmt.Sprintf("page %v isn't linked from page %v", page, req.Page)
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, s.resultPage(page))
+	case strings.HasPrefix(req.Event, "Title$"):
+		row, _ := strconv.Atoi(strings.TrimPrefix(req.Event, "Title$"))
+		index := ResultIndex(req.Page, row, s.perPage())
+		if row >= s.perPage() || index > s.Total {
+			// no such row: the site gives the result page back
+			fmt.Fprint(w, s.resultPage(req.Page))
+			return
+		}
+		fmt.Fprint(w, fakeTitlePage(index))
+	default:
+		http.Error(w, "unknown postback", http.StatusBadRequest)
+	}
+}
+
+// pagerLinks returns the pages the pager of the given search result page links to (see pagerButtons).
+func (s *fakeSite) pagerLinks(page int) map[int]bool {
+	links := make(map[int]bool)
+	first := (page-1)/pagerButtons*pagerButtons + 1
+	for p := first - 1; p <= first+pagerButtons; p++ {
+		if p >= 1 && p <= s.lastPage() && p != page {
+			links[p] = true
+		}
+	}
+	return links
+}
+
+// resultPage returns the given search result page.
+func (s *fakeSite) resultPage(page int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<html><body><form method="post" action="./" id="form1">%s`, fakeMagic(page))
+	fmt.Fprintf(&b, `<span id="lblCount">%v records found</span>`, s.Total)
+	b.WriteString(`<table id="gvResult">`)
+	for row := 0; row < s.perPage(); row++ {
+		index := ResultIndex(page, row, s.perPage())
+		if index > s.Total {
+			break
+		}
+		fmt.Fprintf(&b, `<tr><td><a href="javascript:__doPostBack('gvResult','Title$%v')">%s</a></td></tr>`, row, fakeName(index))
+	}
+	b.WriteString(`<tr><td>`)
+	links := s.pagerLinks(page)
+	for p := page - pagerButtons - 1; p <= page+pagerButtons+1; p++ {
+		if !links[p] {
+			continue
+		}
+		fmt.Fprintf(&b, `<a href="javascript:__doPostBack('gvResult','Page$%v')">%v</a>`, p, p)
+	}
+	b.WriteString(`</td></tr></table></form></body></html>`)
+	return b.String()
+}
+
+// fakeMagic returns the hidden ASP.NET fields of a page of the session on the given search result page (zero for none).
+func fakeMagic(page int) string {
+	return fmt.Sprintf(`<input type="hidden" name="__VIEWSTATE" id="__VIEWSTATE" value="page-%v" />
+<input type="hidden" name="__VIEWSTATEGENERATOR" id="__VIEWSTATEGENERATOR" value="808F470E" />
+<input type="hidden" name="__EVENTVALIDATION" id="__EVENTVALIDATION" value="valid" />`, page)
+}
+
+// fakeSearchForm is the fakeSite's search form, which starts a session.
+var fakeSearchForm = `<html><body><form method="post" action="./" id="form1">` + fakeMagic(0) + `
+<input type="text" name="txtTitle" id="txtTitle" />
+<input type="checkbox" name="chklstType$0" value="Feature" />
+<input type="submit" name="btnSearch" id="btnSearch" value="Search" />
+</form></body></html>`
+
+// fakeName returns the name of the fakeSite's title with the given index.
+func fakeName(index int) string {
+	return fmt.Sprintf("TITLE %v", index)
+}
+
+// fakeID returns the record ID of the fakeSite's title with the given index.
+func fakeID(index int) string {
+	return fmt.Sprintf("ROW%06d", index)
+}
+
+// fakeTitlePage returns the title page of the fakeSite's title with the given index.
+func fakeTitlePage(index int) string {
+	return fmt.Sprintf(`<html><body><form method="post" action="SearchDetail.aspx?sType=Feature&amp;sRowID=%s" id="form1">%s
+<div id="content"><span id="lblTitle">%s</span>
+<table>
+<tr><td><b>Format</b></td><td><b>Region</b></td><td><b>Rating</b></td><td><b>Decision</b></td><td><b>Duration</b></td><td><b>Distributor</b></td></tr>
+<tr><td>DVD</td><td>N/A</td><td><img src="Rating_PG.png" alt="Parental Guidance" /></td><td>Passed Clean</td><td>90</td><td>FAKE PICTURES</td></tr>
+</table></div></form></body></html>`, fakeID(index), fakeMagic(0), fakeName(index))
+}
+
+// storeResults returns a ResultStore that appends each Result to *results.
+func storeResults(results *[]Result) ResultStore {
+	return ResultStoreFunc(func(r Result) error {
+		*results = append(*results, r)
+		return nil
+	})
+}
+
+// siteTransport is a RoundTripper that answers every request by serving it with an http.Handler, such as a fakeSite, whatever its host, without a network.
+type siteTransport struct {
+	h http.Handler
+}
+
+func (t siteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// titlePage returns a title page for a title with the given record ID, name and ratings, laid out as the site's are. A Rating with an empty Rating is given as text (as for an older record's refusal), any other as an image.
+func titlePage(id string, name string, ratings ...Rating) string {
+	var rows strings.Builder
+	for _, r := range ratings {
+		cell := "-"
+		if r.Rating != "" {
+			cell = fmt.Sprintf(`<img src="Rating.png" alt="%s" />`, r.Rating)
+		}
+		fmt.Fprintf(&rows, "<tr><td>DVD</td><td>N/A</td><td>%s</td><td>%s</td><td>90</td><td>N/A</td></tr>\n", cell, r.Decision)
+	}
+	return fmt.Sprintf(`<html><body><form method="post" action="SearchDetail.aspx?sType=Feature&amp;sRowID=%s" id="form1">
+<div id="content"><span id="lblTitle">%s</span>
+<table>
+<tr><td><b>Format</b></td><td><b>Region</b></td><td><b>Rating</b></td><td><b>Decision</b></td><td><b>Duration</b></td><td><b>Distributor</b></td></tr>
+%s</table></div></form></body></html>`, id, name, rows.String())
+}
+
+// writeFiles writes each of files, keyed by its path relative to dir, creating directories as need be.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package libsuger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SortKeys are the keys SortTitles can sort by.
+var SortKeys = []string{"name", "rating", "id"}
+
+// SortTitles sorts titles in place by key, one of SortKeys: "name" sorts by Name; "rating" sorts by MaxRating, highest first, with titles that have no recognized rating last; "id" sorts by Key. Ties are broken by Name and then Key, so that the order is the same however titles were ordered to begin with.
+func SortTitles(titles []*Title, key string) error {
+	var less func(a, b *Title) bool
+	switch key {
+	case "name":
+		less = func(a, b *Title) bool {
+			return false
+		}
+	case "rating":
+		rank := make(map[*Title]int, len(titles))
+		for _, t := range titles {
+			rank[t] = t.ratingRank()
+		}
+		less = func(a, b *Title) bool {
+			return rank[a] < rank[b]
+		}
+	case "id":
+		less = func(a, b *Title) bool {
+			return a.Key() < b.Key()
+		}
+	default:
+		msg := fmt.Sprintf("%q is not a sort key (want one of %v).", key, SortKeys)
+		return errors.New(msg)
+	}
+	sort.SliceStable(titles, func(i, j int) bool {
+		a, b := titles[i], titles[j]
+		switch {
+		case less(a, b):
+			return true
+		case less(b, a):
+			return false
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		}
+		return a.Key() < b.Key()
+	})
+	return nil
+}
+
+// ratingRank returns the position of the title's MaxRating in the rating order followed by the legacy ratings, or the length of both if it has none.
+func (t *Title) ratingRank() int {
+	all := allRatings()
+	max, ok := t.MaxRating()
+	if !ok {
+		return len(all)
+	}
+	for i, r := range all {
+		if r == max {
+			return i
+		}
+	}
+	return len(all)
+}
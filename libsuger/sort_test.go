@@ -0,0 +1,47 @@
+package libsuger
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSortTitlesStable(t *testing.T) {
+	pg := []Rating{{Rating: "Parental Guidance", Decision: "Passed Clean"}}
+	r21 := []Rating{{Rating: "Restricted 21", Decision: "Passed Clean"}}
+	titles := []*Title{
+		{ID: "B", Name: "ALPHA", Ratings: pg},
+		{ID: "A", Name: "ALPHA", Ratings: r21}, // same name: broken by ID
+		{ID: "C", Name: "BETA"},
+		{URL: "https://example.com/d", Name: "GAMMA", Ratings: r21},
+		{ID: "E", Name: "DELTA", Ratings: pg},
+	}
+	want := map[string][]string{
+		"name":   {"A", "B", "C", "E", "https://example.com/d"},
+		"rating": {"A", "https://example.com/d", "B", "E", "C"},
+		"id":     {"A", "B", "C", "E", "https://example.com/d"},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for key, keys := range want {
+		for i := 0; i < 20; i++ {
+			shuffled := append([]*Title(nil), titles...)
+			rng.Shuffle(len(shuffled), func(i, j int) {
+				shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			})
+			err := SortTitles(shuffled, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, title := range shuffled {
+				got = append(got, title.Key())
+			}
+			if !equalStrings(got, keys) {
+				t.Errorf("sorted by %s: %q, want %q", key, got, keys)
+				break
+			}
+		}
+	}
+	if SortTitles(titles, "date") == nil {
+		t.Error("SortTitles by an unknown key succeeded")
+	}
+}
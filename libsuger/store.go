@@ -0,0 +1,84 @@
+package libsuger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/colinhb/suger/internal/atomicfile"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// ResultStore is where CrawlRange puts the Results it crawls: a directory (see DirStore), an archive, a database, a bucket in the cloud, or memory. Store is only ever called from the goroutine that called CrawlRange, so needn't be safe for concurrent use. An error from Store stops the crawl.
+type ResultStore interface {
+	Store(r Result) error
+}
+
+// ResultStoreFunc adapts an ordinary function to a ResultStore.
+type ResultStoreFunc func(Result) error
+
+// Store calls f(r).
+func (f ResultStoreFunc) Store(r Result) error {
+	return f(r)
+}
+
+// DirStore is the ResultStore that writes each Result's HTML to its own file in a directory, as suger crawl does by default. Each file is replaced atomically, so a crawl that stops part way never leaves one partly written.
+type DirStore struct {
+	Dir  string             // directory the files are written to; it, and any subdirectories the names call for, are created if need be
+	Name *template.Template // names each file (see Result.RenderName); nil means Result.Filename
+	Gzip bool               // gzip each file, adding .gz to its name
+	Perm os.FileMode        // permission of the files; directories also get search permission wherever it allows reading. Zero means 0644
+}
+
+// Path returns the path of the file in s.Dir that r is written to, without the .gz added if s.Gzip is true.
+func (s *DirStore) Path(r Result) (string, error) {
+	name := r.Filename()
+	if s.Name != nil {
+		var err error
+		name, err = r.RenderName(s.Name)
+		if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(s.Dir, name), nil
+}
+
+// Store writes r's HTML to the file given by Path.
+func (s *DirStore) Store(r Result) error {
+	path, err := s.Path(r)
+	if err != nil {
+		return err
+	}
+	perm := s.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	err = os.MkdirAll(filepath.Dir(path), perm|(perm&0444)>>2)
+	if err != nil {
+		return err
+	}
+	html := r.HTML
+	if s.Gzip {
+		path = path + ".gz"
+		html, err = gzipBytes(html)
+		if err != nil {
+			return err
+		}
+	}
+	return atomicfile.WriteFile(path, html, perm)
+}
+
+// gzipBytes returns data gzipped.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,133 @@
+package libsuger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func TestDirStoreGzipScrapesTheSame(t *testing.T) {
+	plain, gzipped := t.TempDir(), t.TempDir()
+	for i, name := range []string{"feature.html", "legacy-ra.html"} {
+		html, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := Result{HTML: html, Page: 1, Row: i}
+		err = (&DirStore{Dir: plain}).Store(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = (&DirStore{Dir: gzipped, Gzip: true}).Store(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(gzipped, r.Filename()+".gz"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(data, html) {
+			t.Errorf("%v was stored uncompressed", name)
+		}
+		data, err = gunzip(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, html) {
+			t.Errorf("%v doesn't gunzip to the page stored", name)
+		}
+	}
+	want, err := ScrapeDir(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ScrapeDir(gzipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("gzipped pages scraped to %+v, want %+v", got, want)
+	}
+}
+
+// countingStore is a ResultStore that counts the Results stored in it, by index, and fails with err once it holds failAfter of them (if failAfter isn't zero).
+type countingStore struct {
+	stored    map[int]int
+	total     int
+	failAfter int
+	err       error
+}
+
+func (s *countingStore) Store(r Result) error {
+	if s.failAfter > 0 && s.total >= s.failAfter {
+		return s.err
+	}
+	if s.stored == nil {
+		s.stored = make(map[int]int)
+	}
+	s.stored[r.Index] = s.stored[r.Index] + 1
+	s.total = s.total + 1
+	return nil
+}
+
+func TestCrawlRangeStore(t *testing.T) {
+	_, srv := newFakeSite(t, 45)
+	store := &countingStore{}
+	sum, err := CrawlRange(context.Background(), 5, 30, 3, store, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.total != 30 || sum.Results != 30 {
+		t.Errorf("stored %v results, summary counts %v, want 30", store.total, sum.Results)
+	}
+	for i := 5; i < 35; i++ {
+		if store.stored[i] != 1 {
+			t.Errorf("result %v stored %v times", i, store.stored[i])
+		}
+	}
+
+	// an error from the store stops the crawl
+	full := errors.New("disk full")
+	store = &countingStore{failAfter: 7, err: full}
+	sum, err = CrawlRange(context.Background(), 5, 30, 3, store, WithBaseURL(srv.URL))
+	if !errors.Is(err, full) {
+		t.Errorf("got error %v, want the store's", err)
+	}
+	if store.total != 7 || sum.Results != 7 {
+		t.Errorf("stored %v results, summary counts %v, want 7", store.total, sum.Results)
+	}
+}
+
+func TestDirStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "html")
+	r := Result{Page: 2, Row: 3, Index: 24, HTML: []byte("<html></html>")}
+	store := &DirStore{Dir: dir}
+	err := store.Store(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := &DirStore{Dir: dir, Name: template.Must(template.New("").Parse("{{.Page}}/{{.Index}}.html")), Perm: 0600}
+	err = named.Store(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, perm := range map[string]os.FileMode{"title-2-3.html": 0644, "2/24.html": 0600} {
+		fi, err := os.Stat(filepath.Join(dir, path))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if fi.Mode().Perm() != perm {
+			t.Errorf("%v has permission %v, want %v", path, fi.Mode().Perm(), perm)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, path))
+		if !bytes.Equal(data, r.HTML) {
+			t.Errorf("%v holds %q", path, data)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package libsuger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ThrottleError is the error returned when the site refuses a request because it is rate-limiting or blocking the client: a 429 Too Many Requests, 503 Service Unavailable or 403 Forbidden response. RetryAfter is the wait the site asked for in its Retry-After header, or zero if it didn't say. CrawlRange answers a ThrottleError by pausing every worker, not just the one that was refused.
+type ThrottleError struct {
+	Status     int
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("throttled by server (%v %v), retry after %v", e.Status, http.StatusText(e.Status), e.RetryAfter)
+	}
+	return fmt.Sprintf("throttled by server (%v %v)", e.Status, http.StatusText(e.Status))
+}
+
+// checkThrottle returns a ThrottleError if resp is a throttle or ban response.
+func checkThrottle(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusForbidden:
+		e := &ThrottleError{Status: resp.StatusCode}
+		secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err == nil && secs > 0 {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		}
+		return e
+	}
+	return nil
+}
+
+// gate holds requests back until a time shared by every Crawler of a crawl, so that one Crawler being throttled pauses them all.
+type gate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// pause holds requests back for d from now, unless they're already held longer.
+func (g *gate) pause(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := time.Now().Add(d)
+	if t.After(g.until) {
+		g.until = t
+	}
+}
+
+// wait waits until the gate opens, returning early with ctx.Err() if ctx is done first.
+func (g *gate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	d := time.Until(g.until)
+	g.mu.Unlock()
+	return sleep(ctx, d)
+}
@@ -0,0 +1,98 @@
+package libsuger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tooManyRequests is the body of a 429 response, as a rate-limiting proxy in front of the site might send it.
+const tooManyRequests = `<html><head><title>429 Too Many Requests</title></head>
+<body><h1>Too Many Requests</h1><p>You have sent too many requests in a given amount of time. Please try again later.</p></body></html>`
+
+func TestCheckThrottle(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		throttled  bool
+		wait       time.Duration
+	}{
+		{http.StatusTooManyRequests, "30", true, 30 * time.Second},
+		{http.StatusTooManyRequests, "", true, 0},
+		{http.StatusTooManyRequests, "Wed, 21 Oct 2015 07:28:00 GMT", true, 0},
+		{http.StatusServiceUnavailable, "5", true, 5 * time.Second},
+		{http.StatusForbidden, "", true, 0},
+		{http.StatusOK, "30", false, 0},
+		{http.StatusInternalServerError, "", false, 0},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		if tt.retryAfter != "" {
+			rec.Header().Set("Retry-After", tt.retryAfter)
+		}
+		rec.WriteHeader(tt.status)
+		fmt.Fprint(rec, tooManyRequests)
+		err := checkThrottle(rec.Result())
+		te, ok := err.(*ThrottleError)
+		if ok != tt.throttled {
+			t.Errorf("%v, Retry-After %q: got error %v, want throttled %v", tt.status, tt.retryAfter, err, tt.throttled)
+			continue
+		}
+		if ok && (te.Status != tt.status || te.RetryAfter != tt.wait) {
+			t.Errorf("%v, Retry-After %q: got %+v, want status %v and wait %v", tt.status, tt.retryAfter, te, tt.status, tt.wait)
+		}
+	}
+}
+
+func TestCrawlThrottlePausesAllWorkers(t *testing.T) {
+	site, srv := newFakeSite(t, 40)
+	var mu sync.Mutex
+	var throttled time.Time
+	var times []time.Time // when each request after the 429 arrived
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if !throttled.IsZero() {
+			times = append(times, time.Now())
+			return false
+		}
+		if r.Event == "Title$5" {
+			throttled = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, tooManyRequests)
+			return true
+		}
+		return false
+	}
+	var results []Result
+	sum, err := CrawlRange(context.Background(), 1, 40, 2, storeResults(&results), WithBaseURL(srv.URL), WithBackoff(BackoffConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Results != 40 || sum.Retries != 1 {
+		t.Errorf("summary %+v, want 40 results after 1 retry", sum)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if throttled.IsZero() {
+		t.Fatal("the site never throttled the crawl")
+	}
+	// the other worker may already have had a request on its way
+	early := 0
+	for _, at := range times {
+		if at.Sub(throttled) < 900*time.Millisecond {
+			early = early + 1
+		}
+	}
+	if early > 1 {
+		t.Errorf("%v requests reached the site during the pause, want at most 1", early)
+	}
+	if len(times) == 0 || times[len(times)-1].Sub(throttled) < time.Second {
+		t.Errorf("the crawl didn't wait out the Retry-After")
+	}
+}
@@ -0,0 +1,56 @@
+package libsuger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Trace describes one HTTP request the Crawler made, and its outcome, for WithTrace.
+type Trace struct {
+	Method  string
+	URL     string
+	Form    url.Values    // the form values posted (including the magic strings), or nil for a GET
+	Status  int           // the response's status code, or zero if the request failed
+	Err     error         // why the request failed, if it did
+	Elapsed time.Duration // from sending the request to receiving the response's headers
+}
+
+// WithTrace calls fn with a Trace of every request the Crawler makes, once its response has arrived (or the request has failed), so that the exact sequence of GETs and postbacks of a crawl can be followed. Like WithRequestInterceptor, it sees each request as it is actually sent, including those following a redirect.
+func WithTrace(fn func(Trace)) CrawlerOption {
+	return WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+		return &tracer{next: next, fn: fn}
+	})
+}
+
+// tracer is a RoundTripper that reports each request it passes on to fn.
+type tracer struct {
+	next http.RoundTripper
+	fn   func(Trace)
+}
+
+func (t *tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	tr := Trace{Method: req.Method, URL: req.URL.String()}
+	if req.Body != nil && req.Method == "POST" {
+		req = req.Clone(req.Context())
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		tr.Form, _ = url.ParseQuery(string(body))
+	}
+	began := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	tr.Elapsed = time.Since(began)
+	if err != nil {
+		tr.Err = err
+	} else {
+		tr.Status = resp.StatusCode
+	}
+	t.fn(tr)
+	return resp, err
+}
@@ -0,0 +1,74 @@
+package libsuger
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestWithTrace(t *testing.T) {
+	site, srv := newFakeSite(t, 25)
+	var traces []Trace
+	var results []Result
+	// results 19 to 22 span pages 1 and 2, so the crawl goes through every
+	// stage: the search form, the search, rows and a page
+	_, err := CrawlRange(context.Background(), 19, 4, 1, storeResults(&results),
+		WithBaseURL(srv.URL), WithTrace(func(tr Trace) { traces = append(traces, tr) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Errorf("got %v results, want 4", len(results))
+	}
+	reqs := site.Requests()
+	if len(traces) != len(reqs) {
+		t.Fatalf("traced %v requests, site got %v", len(traces), len(reqs))
+	}
+	want := []string{"", "Search", "Title$18", "Title$19", "Page$2", "Title$0", "Title$1"}
+	for i, tr := range traces {
+		if i >= len(want) {
+			t.Errorf("extra request %v: %v %v", i, tr.Method, tr.URL)
+			continue
+		}
+		if tr.Method != reqs[i].Method || tr.URL != srv.URL+searchPath {
+			t.Errorf("request %v: traced %v %v, site got %v %v", i, tr.Method, tr.URL, reqs[i].Method, reqs[i].Path)
+		}
+		if tr.Status != http.StatusOK || tr.Err != nil {
+			t.Errorf("request %v: traced status %v (%v), want 200", i, tr.Status, tr.Err)
+		}
+		event := tr.Form.Get("__EVENTARGUMENT")
+		if tr.Form.Get("btnSearch") != "" {
+			event = "Search"
+		}
+		if event != want[i] {
+			t.Errorf("request %v: traced postback %q, want %q", i, event, want[i])
+		}
+		if tr.Method == "POST" && tr.Form.Get("__VIEWSTATE") == "" {
+			t.Errorf("request %v (%v): traced form has no __VIEWSTATE", i, event)
+		}
+	}
+}
+
+func TestWithTraceFailure(t *testing.T) {
+	site, srv := newFakeSite(t, 1)
+	site.Hook = func(w http.ResponseWriter, r fakeRequest, n int) bool {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		return true
+	}
+	var traces []Trace
+	c, err := NewCrawler(WithBaseURL(srv.URL), WithTrace(func(tr Trace) { traces = append(traces, tr) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.doInit(context.Background())
+	if len(traces) != 1 || traces[0].Status != http.StatusServiceUnavailable {
+		t.Errorf("traces %+v, want one with status 503", traces)
+	}
+	// a request that gets no response is traced with its error
+	srv.Close()
+	traces = nil
+	c.doInit(context.Background())
+	if len(traces) != 1 || traces[0].Err == nil || traces[0].Status != 0 {
+		t.Errorf("traces %+v, want one with an error", traces)
+	}
+}
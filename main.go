@@ -1,24 +1,55 @@
 package main
 
 import (
-	"regexp"
-	"bufio"
+	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/colinhb/suger/internal/atomicfile"
 	suger "github.com/colinhb/suger/libsuger"
-	"io/ioutil"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
-	"time"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 )
 
-func signalHandler(ch chan os.Signal) {
+// logger is the leveled logger suger's commands and crawlers report to (see -verbose and -quiet). Fatal errors are still reported with the log package.
+var logger = newLogger(false, false)
+
+// newLogger returns a logger writing to stderr at the Info level, or the Debug level if verbose, or only errors if quiet.
+func newLogger(verbose bool, quiet bool) *slog.Logger {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	if quiet {
+		level = slog.LevelError
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+// exitInterrupted is the exit status of a crawl stopped by an interrupt, following the shell convention of 128 + SIGINT.
+const exitInterrupted = 130
+
+// signalHandler calls cancel on the first signal, so that a crawl can stop its workers, and exits on the next (or the first, if cancel is nil).
+func signalHandler(ch chan os.Signal, cancel context.CancelFunc) {
 	for sig := range ch {
-		log.Println("Caught signal:", sig)
-		os.Exit(0)
+		logger.Warn("caught signal", "signal", sig)
+		if cancel == nil {
+			os.Exit(exitInterrupted)
+		}
+		logger.Warn("stopping workers; interrupt again to exit immediately")
+		cancel()
+		cancel = nil
 	}
 }
 
@@ -31,17 +62,23 @@ func Heredoc(doc string) string {
 	if len(lines) < 3 {
 		return ""
 	}
-	lines = lines[1:len(lines)-1]
+	lines = lines[1 : len(lines)-1]
 
 	// reduce for min tab indent
-	var min string // accumulator
+	var min string                        // accumulator
+	seen := false                         // whether min has been initialized
 	re := regexp.MustCompile(`^(\t*)\S+`) // submatch is 0+ tabs at start of line
 	for i := 0; i < len(lines); i++ {
-		tabs := re.FindStringSubmatch(lines[0])[1] // e.g. "\t\t\t"
-		if i == 0 { // init accumulator 
+		m := re.FindStringSubmatch(lines[i])
+		if m == nil { // blank (or space-indented) line; doesn't count
+			continue
+		}
+		tabs := m[1] // e.g. "\t\t\t"
+		if !seen {   // init accumulator
 			min = tabs
+			seen = true
 			continue
-		} 
+		}
 		if len(tabs) < len(min) { // len() is ok - all runes are "\t"
 			min = tabs
 		}
@@ -49,8 +86,8 @@ func Heredoc(doc string) string {
 
 	// map in-place for unindent
 	for i, s := range lines {
-		lines[i] = strings.Replace(s, min, "", 1) // 1 replaces only left most occurance
-	} 
+		lines[i] = strings.TrimPrefix(s, min) // only strip leading tabs
+	}
 
 	// join lines and return
 	return strings.Join(lines, "\n")
@@ -60,7 +97,8 @@ func main() {
 	// handle ^C
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
-	go signalHandler(ch)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	usage := Heredoc(`
 		Usage of suger:
@@ -68,6 +106,14 @@ func main() {
 				crawl classification database
 			suger scrape [flags]
 				scrape downloaded html files
+			suger diff [flags] old.json new.json
+				compare the output of two scrapes
+			suger fetch [flags] id-or-url...
+				fetch single titles by ID or URL
+			suger list [flags]
+				list titles from the search result pages only
+			suger check [flags]
+				check downloaded html files are title pages
 		(Use the -h flag for help with each subcommand.)
 	`)
 
@@ -79,128 +125,1062 @@ func main() {
 
 	// common flag vars
 	var htmlDir string
+	var verbose bool
+	var quiet bool
 
 	// crawl flag vars
 	var start int
 	var count int
 	var workers int
+	var record string
+	var backoffInitial time.Duration
+	var backoffMax time.Duration
+	var maxAttempts int
+	var delay time.Duration
+	var userAgent string
+	var timeout time.Duration
+	var resume bool
+	var manifestPath string
+	var replay string
+	var gzipped bool
+	var archive string
+	var failuresPath string
+	var retryFailed string
+	var nameTemplate string
+	var dryRun bool
+	var types string
+	var search string
+	var cacheDir string
+	var cookieFile string
+	var proxy string
+	var startURL string
+	var insecure bool
+	var trace bool
+	var chunk int
+	var rate float64
+	var diffAgainst string
+	var progress time.Duration
 
 	// scrape flag vars
-	var out string
+	var sc scrapeConfig
+	var rating string
+	var minRating string
 
-	// crawl flagset 
+	// crawl flagset
 	crawlFlags := flag.NewFlagSet("crawl", flag.ExitOnError)
+	crawlFlags.StringVar(&retryFailed, "retry-failed", "", "only crawl the results listed in this file written by -failures (overrides -start and -count)")
+	crawlFlags.StringVar(&search, "search", "", "only crawl titles matching this search term")
 	crawlFlags.IntVar(&start, "start", 1, "start at this result")
-	crawlFlags.IntVar(&count, "count", 25, "crawl this many results")
+	crawlFlags.StringVar(&startURL, "start-url", "", "URL of the search form to start from, if the site has moved it (default "+suger.DefaultBaseURL+"/Classification/Search/Film/)")
+	crawlFlags.StringVar(&archive, "archive", "", "write HTML files into this new .zip, .tar.gz or .tgz archive instead of the html directory")
+	crawlFlags.StringVar(&cacheDir, "cache-dir", "", "directory to cache HTTP responses in, and serve repeated requests from (for development; off by default)")
+	crawlFlags.StringVar(&cookieFile, "cookie-file", "", "file to keep the session in (cookies, and the search), so a later crawl can carry on the same session without searching again")
+	crawlFlags.IntVar(&chunk, "chunk", 0, "split the crawl into chunks of this many results, each worker taking the next as it finishes one (default one chunk per worker)")
+	crawlFlags.IntVar(&count, "count", 0, "crawl this many results (default all, from -start to the last result)")
 	crawlFlags.StringVar(&htmlDir, "html", "html", "directory to write HTML files")
 	crawlFlags.IntVar(&workers, "workers", 1, "number of workers")
+	crawlFlags.DurationVar(&backoffInitial, "backoff-initial", suger.DefaultBackoff.Initial, "delay before the first retry of a failed job")
+	crawlFlags.DurationVar(&backoffMax, "backoff-max", suger.DefaultBackoff.Max, "maximum delay between retries of a failed job")
+	crawlFlags.StringVar(&diffAgainst, "diff-against", "", "JSON file of titles written by an earlier scrape; list the search result grid first, and only fetch titles whose names aren't in it (new or renamed)")
+	crawlFlags.DurationVar(&delay, "delay", 0, "minimum time between requests made by each worker")
+	crawlFlags.StringVar(&nameTemplate, "name-template", defaultNameTemplate, "Go template naming each HTML file, from {{.Page}}, {{.Row}}, {{.Index}} and {{.URL}}")
+	crawlFlags.IntVar(&maxAttempts, "max-attempts", 5, "give up on a result after this many failed attempts (0 means never)")
+	crawlFlags.DurationVar(&timeout, "timeout", suger.DefaultTimeout, "time limit for each request (0 means none)")
+	crawlFlags.BoolVar(&verbose, "verbose", false, "log debugging detail, such as each request")
+	crawlFlags.BoolVar(&quiet, "quiet", false, "only log errors")
+	crawlFlags.BoolVar(&trace, "trace", false, "log the method, URL and status of every request, and the postback event of each form posted")
+	crawlFlags.StringVar(&types, "types", strings.Join(suger.SearchTypes, ","), "comma-separated classification types to search for: "+strings.Join(suger.SearchTypes, ", "))
+	crawlFlags.StringVar(&userAgent, "user-agent", "", "User-Agent header to send (default Go's)")
+	crawlFlags.StringVar(&manifestPath, "manifest", "", "file recording which results have been crawled; results already in it are skipped")
+	crawlFlags.BoolVar(&dryRun, "dry-run", false, "print each worker's range, the pages it will request and the files it will write, without crawling (needs -count)")
+	crawlFlags.StringVar(&failuresPath, "failures", "", "file to write the results given up on to, as JSON, for -retry-failed")
+	crawlFlags.BoolVar(&gzipped, "gzip", false, "gzip HTML files (written as title-PAGE-ROW.html.gz)")
+	crawlFlags.BoolVar(&resume, "resume", false, "skip results already downloaded to the html directory")
+	crawlFlags.DurationVar(&progress, "progress", 0, "log how many results are done, the request rate and the time left this often (0 means never)")
+	crawlFlags.Float64Var(&rate, "rate", 0, "maximum requests per second, across all workers (0 means no limit)")
+	crawlFlags.StringVar(&proxy, "proxy", "", "send requests through this http, https or socks5 proxy (e.g. socks5://localhost:1080)")
+	crawlFlags.BoolVar(&insecure, "insecure", false, "don't verify the server's TLS certificate (for debugging only)")
+	crawlFlags.StringVar(&record, "record", "", "directory to record HTTP responses to")
+	crawlFlags.StringVar(&replay, "replay", "", "directory to replay recorded HTTP responses from (no network)")
+	crawlFlags.Var(permFlag{&filePerm}, "perm", "permission of files written (directories created also get search permission)")
 
 	// scrape flagset
 	scrapeFlags := flag.NewFlagSet("scrape", flag.ExitOnError)
 	scrapeFlags.StringVar(&htmlDir, "html", "html", "directory to read HTML files")
-	scrapeFlags.StringVar(&out, "out", "out", "directory for output")
+	scrapeFlags.BoolVar(&sc.appendOut, "append", false, "merge the titles into an existing out.json, replacing those with the same ID (or URL), instead of overwriting it (json only)")
+	scrapeFlags.StringVar(&sc.archive, "archive", "", "read HTML files from this .zip, .tar.gz or .tgz archive instead of the html directory")
+	scrapeFlags.BoolVar(&sc.envelope, "envelope", false, "write json as an object recording the suger_version, crawled_at time and count of the titles, with the titles array in it (json only)")
+	scrapeFlags.StringVar(&sc.out, "out", "out", "directory for output, or - to write it to standard output (not for sqlite or -flush-every)")
+	scrapeFlags.StringVar(&sc.glob, "glob", "", "only scrape files whose names match this pattern (default *.html and *.htm, gzipped or not; .gz files are decompressed)")
+	scrapeFlags.StringVar(&sc.format, "format", "json", "output format: json, csv, ndjson (one JSON object per line, written as each file is scraped), or sqlite (a database of titles and ratings, updated by later scrapes)")
+	scrapeFlags.IntVar(&sc.flushEvery, "flush-every", 0, "write titles to numbered chunk files (out-0001.json, ...) of at most this many titles")
+	scrapeFlags.BoolVar(&sc.fatal, "fatal", false, "stop at the first file that can't be scraped")
+	scrapeFlags.IntVar(&sc.limit, "limit", 0, "stop after scraping this many titles, from the first files in order (0 means no limit)")
+	scrapeFlags.BoolVar(&sc.mergeDups, "merge-dups", false, "add ratings found only on a duplicate title to the first one kept")
+	scrapeFlags.StringVar(&rating, "rating", "", "only output titles whose maximum rating is this (e.g. \"Restricted 21\"), or none for titles with no recognized rating")
+	scrapeFlags.StringVar(&minRating, "min-rating", "", "only output titles whose maximum rating is this or higher")
+	scrapeFlags.BoolVar(&sc.onlyRefused, "only-refused", false, "only output titles with a refused (banned or NAR) decision")
+	scrapeFlags.BoolVar(&verbose, "verbose", false, "log debugging detail, such as files passed over")
+	scrapeFlags.BoolVar(&quiet, "quiet", false, "only log errors")
+	scrapeFlags.IntVar(&sc.workers, "workers", runtime.NumCPU(), "number of files to parse concurrently")
+	scrapeFlags.Var(permFlag{&filePerm}, "perm", "permission of files written (directories created also get search permission)")
+	scrapeFlags.BoolVar(&sc.recursive, "recursive", false, "also scrape files in subdirectories of the html directory")
+	scrapeFlags.StringVar(&sc.sortKey, "sort", "name", "sort titles by name, rating, or id, or none to keep the order files are scraped in, which lets json be written as each file is scraped (not for ndjson or sqlite, which are written unsorted; with -flush-every, each chunk is sorted)")
+
+	// diff flagset
+	var diffOut string
+	diffFlags := flag.NewFlagSet("diff", flag.ExitOnError)
+	diffFlags.StringVar(&diffOut, "out", "", "file to write the diff to (default standard output)")
+
+	// fetch flagset
+	var fetchScrape bool
+	fetchFlags := flag.NewFlagSet("fetch", flag.ExitOnError)
+	fetchFlags.StringVar(&htmlDir, "html", "html", "directory to write HTML files (named title-ID.html)")
+	fetchFlags.BoolVar(&fetchScrape, "scrape", false, "scrape the titles and write them to standard output as JSON, instead of writing HTML files")
+	fetchFlags.DurationVar(&timeout, "timeout", suger.DefaultTimeout, "time limit for each request (0 means none)")
+	fetchFlags.StringVar(&userAgent, "user-agent", "", "User-Agent header to send (default Go's)")
+	fetchFlags.BoolVar(&verbose, "verbose", false, "log debugging detail, such as each request")
+
+	// list flagset
+	var firstPage int
+	var lastPage int
+	var listOut string
+	listFlags := flag.NewFlagSet("list", flag.ExitOnError)
+	listFlags.IntVar(&firstPage, "first-page", 1, "first search result page to list")
+	listFlags.IntVar(&lastPage, "last-page", 0, "last search result page to list (default the last page)")
+	listFlags.StringVar(&listOut, "out", "", "file to write the list to (default standard output)")
+	listFlags.DurationVar(&delay, "delay", 0, "minimum time between requests")
+	listFlags.StringVar(&search, "search", "", "only list titles matching this search term")
+	listFlags.DurationVar(&timeout, "timeout", suger.DefaultTimeout, "time limit for each request (0 means none)")
+	listFlags.StringVar(&types, "types", strings.Join(suger.SearchTypes, ","), "comma-separated classification types to search for: "+strings.Join(suger.SearchTypes, ", "))
+	listFlags.StringVar(&userAgent, "user-agent", "", "User-Agent header to send (default Go's)")
+	listFlags.BoolVar(&verbose, "verbose", false, "log debugging detail, such as each request")
+
+	// check flagset
+	var checkArchive string
+	checkScraper := &suger.Scraper{Strict: true}
+	checkFlags := flag.NewFlagSet("check", flag.ExitOnError)
+	checkFlags.StringVar(&htmlDir, "html", "html", "directory to read HTML files")
+	checkFlags.StringVar(&checkArchive, "archive", "", "read HTML files from this .zip, .tar.gz or .tgz archive instead of the html directory")
+	checkFlags.StringVar(&checkScraper.Glob, "glob", "", "only check files whose names match this pattern (default *.html and *.htm, gzipped or not; .gz files are decompressed)")
+	checkFlags.BoolVar(&checkScraper.Recursive, "recursive", false, "also check files in subdirectories of the html directory")
+	checkFlags.IntVar(&checkScraper.Workers, "workers", runtime.NumCPU(), "number of files to check concurrently")
+	checkFlags.BoolVar(&verbose, "verbose", false, "log debugging detail, such as files passed over")
 
 	// switch on subcommand
 	switch os.Args[1] {
-		case "crawl":
-			crawlFlags.Parse(os.Args[2:])
-			crawlCmd(start, count, htmlDir, workers)
-		case "scrape":
-			scrapeFlags.Parse(os.Args[2:])
-			scrapeCmd(htmlDir, out)
+	case "crawl":
+		go signalHandler(ch, cancel)
+		crawlFlags.Parse(os.Args[2:])
+		logger = newLogger(verbose, quiet)
+		backoff := suger.DefaultBackoff
+		backoff.Initial = backoffInitial
+		backoff.Max = backoffMax
+		opts := []suger.CrawlerOption{
+			suger.WithBackoff(backoff),
+			suger.WithDelay(delay),
+			suger.WithTimeout(timeout),
+			suger.WithMaxAttempts(maxAttempts),
+			suger.WithLogger(logger),
+		}
+		if userAgent != "" {
+			opts = append(opts, suger.WithUserAgent(userAgent))
+		}
+		if proxy != "" {
+			// it must come before options that wrap the transport
+			opt := suger.WithProxy(proxy)
+			if _, err := suger.NewCrawler(opt); err != nil {
+				fmt.Printf("Error: bad -proxy: %v\n", err)
+				os.Exit(2)
+			}
+			opts = append(opts, opt)
+		}
+		if insecure {
+			// it must come before options that wrap the transport
+			logger.Warn("not verifying TLS certificates")
+			opts = append(opts, suger.WithInsecureTLS(true))
+		}
+		if chunk < 0 {
+			fmt.Printf("Error: -chunk must not be negative (got %v).\n", chunk)
+			os.Exit(2)
+		}
+		if chunk > 0 {
+			opts = append(opts, suger.WithChunkSize(chunk))
+		}
+		if startURL != "" {
+			opts = append(opts, suger.WithStartURL(startURL))
+		}
+		opts = append(opts, suger.WithSearchTypes(strings.Split(types, ",")...))
+		if search != "" {
+			opts = append(opts, suger.WithSearchTerm(search))
+		}
+		if count < 0 {
+			fmt.Printf("Error: -count must not be negative (got %v).\n", count)
+			os.Exit(2)
+		}
+		// -count 0 means up to the last result; check -start alone
+		if _, err := suger.NewJob(start, max(count, 1)); err != nil {
+			fmt.Printf("Error: bad -start or -count: %v\n", err)
+			os.Exit(2)
+		}
+		tmpl, err := parseNameTemplate(nameTemplate)
+		if err != nil {
+			fmt.Printf("Error: bad -name-template: %v\n", err)
+			os.Exit(2)
+		}
+		if dryRun {
+			err = dryRunCmd(start, count, workers, chunk, htmlDir, archive, tmpl)
+			if err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		var manifest *suger.Manifest
+		var skips []func(int, int) bool
+		if archive != "" && (resume || gzipped) {
+			fmt.Println("Error: -resume and -gzip can't be used with -archive.")
+			os.Exit(2)
+		}
+		if resume {
+			skips = append(skips, existingResults(htmlDir, tmpl))
+		}
+		if manifestPath != "" {
+			m, err := suger.LoadManifest(manifestPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			manifest = m
+			skips = append(skips, func(page int, row int) bool {
+				return m.Has(suger.ResultIndex(page, row, suger.ResultsPerPage))
+			})
+		}
+		if retryFailed != "" {
+			failures, err := suger.LoadFailures(retryFailed)
+			if err != nil {
+				log.Fatal(err)
+			}
+			var retry func(int, int) bool
+			start, count, retry = failedRange(failures)
+			skips = append(skips, retry)
+		}
+		if record != "" {
+			opts = append(opts, suger.WithRecording(record))
+		}
+		if replay != "" {
+			opts = append(opts, suger.WithReplay(replay))
+		}
+		if progress > 0 {
+			opts = append(opts, suger.WithProgress(0, progress, logProgress))
+		}
+		if rate > 0 {
+			opts = append(opts, suger.WithRate(rate))
+		}
+		if cookieFile != "" {
+			opts = append(opts, suger.WithCookieFile(cookieFile))
+		}
+		if cacheDir != "" {
+			opts = append(opts, suger.WithCache(cacheDir))
+		}
+		if trace {
+			opts = append(opts, suger.WithTrace(logTrace))
+		}
+		if diffAgainst != "" {
+			// the grid is listed with every option but the skips
+			skip, err := deltaSkip(ctx, diffAgainst, start, count, opts)
+			if err != nil {
+				log.Fatal(err)
+			}
+			skips = append(skips, skip)
+		}
+		if len(skips) > 0 {
+			opts = append(opts, suger.WithSkip(anyOf(skips)))
+		}
+		var store suger.ResultStore
+		var aw *suger.ArchiveWriter
+		if archive != "" {
+			var err error
+			aw, err = suger.NewArchiveWriter(archive, filePerm)
+			if err != nil {
+				log.Fatal(err)
+			}
+			store = archiveStore(aw, tmpl)
+		} else {
+			store = dirStore(htmlDir, tmpl, gzipped)
+		}
+		err = crawlCmd(ctx, start, count, workers, store, manifest, failuresPath, opts)
+		if aw != nil {
+			// before any exit, or the archive is unreadable
+			cerr := aw.Close()
+			if cerr != nil {
+				log.Fatal(cerr)
+			}
+		}
+		if errors.Is(err, context.Canceled) {
+			logger.Warn("interrupted; results received so far have been written; use -resume or -manifest to continue")
+			os.Exit(exitInterrupted)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "scrape":
+		go signalHandler(ch, nil)
+		scrapeFlags.Parse(os.Args[2:])
+		logger = newLogger(verbose, quiet)
+		if !validFormat(sc.format) {
+			fmt.Printf("Error: %q is not a valid format.\n", sc.format)
+			os.Exit(2)
+		}
+		if !validSortKey(sc.sortKey) {
+			fmt.Printf("Error: %q is not a valid sort key.\n", sc.sortKey)
+			os.Exit(2)
+		}
+		if sc.out == stdoutName && (sc.format == "sqlite" || sc.flushEvery > 0) {
+			fmt.Println("Error: -out - can't be used with -format sqlite or -flush-every.")
+			os.Exit(2)
+		}
+		if sc.appendOut && (sc.format != "json" || sc.flushEvery > 0 || sc.out == stdoutName) {
+			fmt.Println("Error: -append needs -format json, and can't be used with -flush-every or -out -.")
+			os.Exit(2)
+		}
+		if sc.limit < 0 {
+			fmt.Printf("Error: -limit must not be negative (got %v).\n", sc.limit)
+			os.Exit(2)
+		}
+		if sc.envelope && sc.format != "json" {
+			fmt.Println("Error: -envelope needs -format json.")
+			os.Exit(2)
+		}
+		if _, err := filepath.Match(sc.glob, ""); err != nil {
+			fmt.Printf("Error: %q is not a valid pattern.\n", sc.glob)
+			os.Exit(2)
+		}
+		keep, err := ratingFilter(rating, minRating)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+		sc.keep = keep
+		sc.htmlDir = htmlDir
+		scrapeCmd(sc)
+	case "diff":
+		go signalHandler(ch, nil)
+		diffFlags.Parse(os.Args[2:])
+		if diffFlags.NArg() != 2 {
+			fmt.Println("Error: diff needs two files, old and new.")
+			diffFlags.Usage()
+			os.Exit(2)
+		}
+		err := diffCmd(diffFlags.Arg(0), diffFlags.Arg(1), diffOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "fetch":
+		go signalHandler(ch, cancel)
+		fetchFlags.Parse(os.Args[2:])
+		logger = newLogger(verbose, false)
+		if fetchFlags.NArg() == 0 {
+			fmt.Println("Error: fetch needs at least one title ID or URL.")
+			fetchFlags.Usage()
+			os.Exit(2)
+		}
+		opts := []suger.CrawlerOption{
+			suger.WithTimeout(timeout),
+			suger.WithLogger(logger),
+		}
+		if userAgent != "" {
+			opts = append(opts, suger.WithUserAgent(userAgent))
+		}
+		err := fetchCmd(ctx, fetchFlags.Args(), htmlDir, fetchScrape, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "list":
+		go signalHandler(ch, cancel)
+		listFlags.Parse(os.Args[2:])
+		logger = newLogger(verbose, false)
+		opts := []suger.CrawlerOption{
+			suger.WithDelay(delay),
+			suger.WithTimeout(timeout),
+			suger.WithLogger(logger),
+			suger.WithSearchTypes(strings.Split(types, ",")...),
+		}
+		if userAgent != "" {
+			opts = append(opts, suger.WithUserAgent(userAgent))
+		}
+		if search != "" {
+			opts = append(opts, suger.WithSearchTerm(search))
+		}
+		err := listCmd(ctx, firstPage, lastPage, listOut, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "check":
+		go signalHandler(ch, nil)
+		checkFlags.Parse(os.Args[2:])
+		logger = newLogger(verbose, false)
+		checkScraper.Logger = logger
+		if _, err := filepath.Match(checkScraper.Glob, ""); err != nil {
+			fmt.Printf("Error: %q is not a valid pattern.\n", checkScraper.Glob)
+			os.Exit(2)
+		}
+		bad, err := checkCmd(checkScraper, htmlDir, checkArchive)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if bad > 0 {
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("Error: %q is not valid subcommand.\n", os.Args[1])
+		fmt.Println(usage)
+	}
+}
+
+// checkCmd() is called by the switch in main(). It checks, with scraper (which is Strict), that every HTML file in htmlDir, or in archive if it isn't empty, is a title page that scrapes without warnings, as a crawl that went wrong (e.g. one blocked by the site) may have saved other pages. It prints each bad file with what is wrong with it, logs how many files are of each kind, and returns the number of bad files.
+func checkCmd(scraper *suger.Scraper, htmlDir string, archive string) (int, error) {
+	var valid, empty, results, forms, blocked, other int
+	ok := func(t *suger.Title) error {
+		valid = valid + 1
+		return nil
+	}
+	bad := func(e *suger.FileError) error {
+		switch {
+		case errors.Is(e, suger.ErrEmptyPage):
+			empty = empty + 1
+		case errors.Is(e, suger.ErrNoSuchRow):
+			results = results + 1
+		case errors.Is(e, suger.ErrSessionExpired):
+			forms = forms + 1
+		case errors.Is(e, suger.ErrBlockedPage):
+			blocked = blocked + 1
 		default:
-			fmt.Printf("Error: %q is not valid subcommand.\n", os.Args[1])
-			fmt.Println(usage)
+			other = other + 1
+		}
+		fmt.Println(e)
+		return nil
+	}
+	var err error
+	if archive != "" {
+		err = scraper.ScrapeArchiveFunc(archive, ok, bad)
+	} else {
+		err = scraper.ScrapeDirFunc(htmlDir, ok, bad)
+	}
+	if err != nil {
+		return 0, err
+	}
+	logger.Info("checked files",
+		"valid", valid,
+		"empty", empty,
+		"search_results", results,
+		"search_forms", forms,
+		"blocked", blocked,
+		"other", other,
+	)
+	return empty + results + forms + blocked + other, nil
+}
+
+// crawlCmd() is called by the switch in main(). It crawls with suger.CrawlRange, putting each result in store (and then recording it in manifest, if there is one). At the end it logs a summary, listing the results given up on, and saves those to failuresPath if it isn't empty.
+func crawlCmd(ctx context.Context, start int, count int, workers int, store suger.ResultStore, manifest *suger.Manifest, failuresPath string, opts []suger.CrawlerOption) error {
+	if count == 0 {
+		count = countRemaining(ctx, start, opts)
+	}
+	workers, err := capWorkers(workers, count)
+	if err != nil {
+		return err
+	}
+	logger.Info("crawling", "count", count, "start", start, "workers", workers)
+	record := suger.ResultStoreFunc(func(r suger.Result) error {
+		err := store.Store(r)
+		if err != nil {
+			return err
+		}
+		if manifest != nil {
+			return manifest.Add(r.Index)
+		}
+		return nil
+	})
+	sum, err := suger.CrawlRange(ctx, start, count, workers, record, opts...)
+	logger.Info("crawl summary",
+		"results", sum.Results,
+		"missing", sum.Missing,
+		"failed", sum.Failed,
+		"retries", sum.Retries,
+		"requests", sum.Requests,
+		"elapsed", sum.Elapsed.Round(time.Second),
+		"requests_per_second", fmt.Sprintf("%.2f", sum.RequestsPerSecond()),
+	)
+	for _, f := range sum.Failures {
+		logger.Warn("failed result", "index", f.Index, "page", f.Page, "row", f.Row, "error", f.Error)
+	}
+	if failuresPath != "" {
+		serr := suger.SaveFailures(failuresPath, sum.Failures)
+		if serr != nil {
+			logger.Error("couldn't save failures", "error", serr)
+		}
+	}
+	return err
+}
+
+// capWorkers returns the number of workers to crawl count results with: workers, or count if that is fewer (with a warning), as there is no use for more workers than results. It returns an error if workers is less than one.
+func capWorkers(workers int, count int) (int, error) {
+	if workers < 1 {
+		msg := fmt.Sprintf("the number of workers (%v) must be at least one.", workers)
+		return 0, errors.New(msg)
+	}
+	if count > 0 && workers > count {
+		logger.Warn("more workers than results; using one per result", "workers", workers, "count", count)
+		return count, nil
+	}
+	return workers, nil
+}
+
+// logProgress logs how far the crawl has got, for -progress.
+func logProgress(p suger.Progress) {
+	logger.Info("progress",
+		"done", fmt.Sprintf("%v/%v", p.Done, p.Total),
+		"percent", fmt.Sprintf("%.0f", 100*p.Fraction()),
+		"requests_per_second", fmt.Sprintf("%.2f", p.RequestsPerSecond()),
+		"eta", p.Remaining().Round(time.Second),
+	)
+}
+
+// logTrace logs a request made by the crawl, for -trace.
+func logTrace(t suger.Trace) {
+	args := []any{"method", t.Method, "url", t.URL, "status", t.Status, "elapsed", t.Elapsed}
+	if t.Form != nil {
+		// e.g. "gvResult Page$2", or empty for the search itself
+		event := strings.TrimSpace(t.Form.Get("__EVENTTARGET") + " " + t.Form.Get("__EVENTARGUMENT"))
+		args = append(args, "event", event)
+	}
+	if t.Err != nil {
+		args = append(args, "error", t.Err)
+	}
+	logger.Info("request", args...)
+}
+
+// dryRunCmd() is called by the switch in main() for crawl -dry-run. It prints the plan suger.CrawlRange would follow (see suger.Plan, or suger.PlanChunks with -chunk), without making any requests.
+func dryRunCmd(start int, count int, workers int, chunk int, htmlDir string, archive string, tmpl *template.Template) error {
+	if count == 0 {
+		return errors.New("-dry-run needs -count, since finding the total takes a request")
+	}
+	label := "Worker"
+	parts, err := suger.Plan(start, count, workers)
+	if chunk > 0 {
+		label = "Chunk"
+		parts, err = suger.PlanChunks(start, count, chunk)
+	}
+	if err != nil {
+		return err
+	}
+	name := func(index int) (string, error) {
+		page, row := suger.ResultPosition(index, suger.ResultsPerPage)
+		r := suger.Result{Page: page, Row: row, Index: index}
+		if archive != "" {
+			return r.RenderName(tmpl)
+		}
+		return resultFile(htmlDir, tmpl, r)
+	}
+	for i, j := range parts {
+		first, last := j.Pages(suger.ResultsPerPage)
+		firstName, err := name(j.Start())
+		if err != nil {
+			return err
+		}
+		lastName, err := name(j.Stop() - 1)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v %v: results %v-%v (%v)\n", label, i+1, j.Start(), j.Stop()-1, j.Count())
+		fmt.Printf("  seek: %v\n", j.SeekPages(suger.ResultsPerPage))
+		fmt.Printf("  pages: %v-%v\n", first, last)
+		fmt.Printf("  files: %v ... %v\n", firstName, lastName)
+	}
+	if archive != "" {
+		fmt.Printf("Files would be written into %v.\n", archive)
+	}
+	return nil
+}
+
+// failedRange returns the start and count of the smallest range covering failures, and a skip predicate (see suger.WithSkip) passing over the results in it that aren't among them.
+func failedRange(failures []suger.FailedResult) (int, int, func(int, int) bool) {
+	want := make(map[int]bool)
+	first, last := failures[0].Index, failures[0].Index
+	for _, f := range failures {
+		want[f.Index] = true
+		if f.Index < first {
+			first = f.Index
+		}
+		if f.Index > last {
+			last = f.Index
+		}
 	}
+	skip := func(page int, row int) bool {
+		return !want[suger.ResultIndex(page, row, suger.ResultsPerPage)]
+	}
+	return first, last - first + 1, skip
+}
+
+// writtenFiles records the files written by a crawl, by name, with a hash of their contents, to catch two results being given the same name (e.g. by overlapping partitions or a -name-template that doesn't tell them apart). It is safe for concurrent use.
+type writtenFiles struct {
+	mu   sync.Mutex
+	sums map[string][sha256.Size]byte
 }
 
+// written is the record of the files written by this run's crawl.
+var written = &writtenFiles{sums: make(map[string][sha256.Size]byte)}
 
-// crawlCmd() is called by the switch in main()
-func crawlCmd(start int, count int, htmlDir string, workers int) {
-	// make channels
-	jobs := make(chan suger.Job, workers)
-	results := make(chan suger.Result, workers)
-	done := make(chan bool, workers)
+// add records that data is to be written to name, and returns true if it should be. If name was already written this run with the same data, there is nothing to do; if with different data, it warns of the collision and keeps the first. Either way it returns false.
+func (wf *writtenFiles) add(name string, data []byte) bool {
+	sum := sha256.Sum256(data)
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+	prev, ok := wf.sums[name]
+	if !ok {
+		wf.sums[name] = sum
+		return true
+	}
+	if prev != sum {
+		logger.Warn("two results have the same file name; keeping the first", "file", name)
+	}
+	return false
+}
 
-	
-	j, err := suger.NewJob(start, count)
+// dirStore returns the crawl store writing each result to its own file in htmlDir, named by tmpl, gzipped if gzipped is true (see suger.DirStore). It creates htmlDir if need be.
+func dirStore(htmlDir string, tmpl *template.Template, gzipped bool) suger.ResultStore {
+	err := os.MkdirAll(htmlDir, dirPerm())
 	if err != nil {
 		log.Fatal(err)
 	}
-	parts, err := j.Partition(workers)
-	log.Println("Parts:", parts)
-	for i := 0; i < len(parts); i++ {
-		jobs <- parts[i]	
+	ds := &suger.DirStore{Dir: htmlDir, Name: tmpl, Gzip: gzipped, Perm: filePerm}
+	return suger.ResultStoreFunc(func(r suger.Result) error {
+		file, err := ds.Path(r)
+		if err != nil {
+			return err
+		}
+		if !written.add(file, r.HTML) {
+			return nil
+		}
+		return ds.Store(r)
+	})
+}
+
+// archiveStore returns the crawl store adding each result to aw, named by tmpl.
+func archiveStore(aw *suger.ArchiveWriter, tmpl *template.Template) suger.ResultStore {
+	return suger.ResultStoreFunc(func(r suger.Result) error {
+		name, err := r.RenderName(tmpl)
+		if err != nil {
+			return err
+		}
+		if !written.add(name, r.HTML) {
+			return nil
+		}
+		return aw.Add(name, r.HTML)
+	})
+}
+
+// defaultCount is the number of results crawled when -count is omitted and the total number of results can't be found.
+const defaultCount = 25
+
+// countRemaining returns the number of results from start to the last result, as found by a search. If the total can't be found, it warns and returns defaultCount.
+func countRemaining(ctx context.Context, start int, opts []suger.CrawlerOption) int {
+	c, err := suger.NewCrawler(opts...)
+	if err != nil {
+		log.Fatal(err)
 	}
+	total, err := c.CountResults(ctx)
+	if err != nil {
+		logger.Warn("couldn't find the total number of results", "error", err, "count", defaultCount)
+		return defaultCount
+	}
+	if start > total {
+		log.Fatalf("start (%v) is beyond the last result (%v).", start, total)
+	}
+	logger.Info("found results", "total", total)
+	return total - start + 1
+}
+
+// defaultNameTemplate is the default -name-template: files are named by the page and row of their result.
+const defaultNameTemplate = "title-{{.Page}}-{{.Row}}.html"
+
+// parseNameTemplate parses a -name-template, and checks that it renders a name for a sample Result.
+func parseNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := suger.Result{Page: 1, Row: 0, URL: suger.DefaultBaseURL + "/Classification/Search/Film/SearchDetail.aspx"}
+	_, err = sample.RenderName(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
 
-	remaining := len(parts)
+// resultFile returns the path of the file in htmlDir that r is written to, named by tmpl.
+func resultFile(htmlDir string, tmpl *template.Template, r suger.Result) (string, error) {
+	name, err := r.RenderName(tmpl)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(htmlDir, name), nil
+}
+
+// existingResults returns a predicate reporting whether the result file for a page and row, named by tmpl, gzipped or not, is already in htmlDir. A result's URL isn't known until it is fetched, so names that depend on it are never found.
+func existingResults(htmlDir string, tmpl *template.Template) func(page int, row int) bool {
+	logger.Info("resuming", "dir", htmlDir)
+	return func(page int, row int) bool {
+		file, err := resultFile(htmlDir, tmpl, suger.Result{Page: page, Row: row})
+		if err != nil {
+			return false
+		}
+		if _, err := os.Stat(file); err == nil {
+			return true
+		}
+		_, err = os.Stat(file + ".gz")
+		return err == nil
+	}
+}
 
-	for {
-		select {
-		case j := <-jobs:
-			log.Println("Received Job:", j)
-			if j.Error != nil {
-				log.Println(j.Error)
-				log.Printf("Sleeping for 30 seconds because of error.\n")
-				time.Sleep(time.Second * 30)
+// anyOf returns a skip predicate that is true if any of preds is.
+func anyOf(preds []func(int, int) bool) func(int, int) bool {
+	return func(page int, row int) bool {
+		for _, pred := range preds {
+			if pred(page, row) {
+				return true
 			}
-			if j.IsDone() {
-				done <- true
-			} else {
-				c, _ := suger.NewCrawler()
-				go c.Crawl(j, results, jobs)
+		}
+		return false
+	}
+}
+
+// scrapeConfig holds the settings of the scrape subcommand.
+type scrapeConfig struct {
+	htmlDir     string
+	out         string
+	format      string
+	sortKey     string
+	onlyRefused bool
+	flushEvery  int
+	fatal       bool
+	mergeDups   bool
+	workers     int
+	glob        string
+	recursive   bool
+	archive     string
+	keep        func(*suger.Title) bool // if not nil, only titles it is true for are output
+	appendOut   bool                    // merge into an existing out.json (see suger.MergeTitles)
+	envelope    bool                    // write json in an envelope (see envelope)
+	limit       int                     // stop after this many titles (see suger.Scraper.Limit)
+}
+
+// ratingFilter returns the filter for the scrape flags -rating and -min-rating (empty if not given), or nil if neither is given. Titles with no recognized rating are only kept by -rating none.
+func ratingFilter(rating string, minRating string) (func(*suger.Title) bool, error) {
+	var filters []func(*suger.Title) bool
+	switch rating {
+	case "":
+	case "none":
+		filters = append(filters, suger.Unrated)
+	default:
+		f, err := suger.MaxRatingIs(rating)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if minRating != "" {
+		f, err := suger.MaxRatingAtLeast(minRating)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return func(t *suger.Title) bool {
+		for _, f := range filters {
+			if !f(t) {
+				return false
 			}
-		case r := <-results:
-			file := fmt.Sprintf("%v/title-%v-%v.html", htmlDir, r.Page, r.Row)
-			err = ioutil.WriteFile(file, r.HTML, 0644)
+		}
+		return true
+	}, nil
+}
+
+func scrapeCmd(sc scrapeConfig) {
+	if sc.archive == "" {
+		fi, err := os.Stat(sc.htmlDir)
+		if err != nil {
+			log.Fatalf("Can't read html directory: %v", err)
+		}
+		if !fi.IsDir() {
+			log.Fatalf("%v is not a directory.", sc.htmlDir)
+		}
+	}
+	// outFile returns the name of the output file with extension ext
+	outFile := func(ext string) string {
+		if sc.out == stdoutName {
+			return stdoutName
+		}
+		return fmt.Sprintf("%s/out.%s", sc.out, ext)
+	}
+	if sc.out != stdoutName {
+		err := os.MkdirAll(sc.out, dirPerm())
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	var titles []*suger.Title
+	var failures []*suger.FileError
+	var stream titleStream
+	chunk := 0
+	warned := 0 // titles with warnings
+	dedup := &suger.Deduper{Merge: sc.mergeDups}
+	switch sc.format {
+	case "ndjson":
+		stream = newNDJSONWriter(outFile("ndjson"))
+	case "sqlite":
+		stream = newSQLiteWriter(outFile("sqlite"))
+	case "json":
+		// unsorted, and in one file, there's no need to hold the titles
+		if sc.sortKey == "none" && sc.flushEvery == 0 && !sc.appendOut {
+			stream = newJSONArrayWriter(outFile("json"), sc.envelope)
+		}
+	}
+	// write sorts titles and writes them to fileName
+	write := func(fileName string) {
+		if sc.sortKey != "none" {
+			err := suger.SortTitles(titles, sc.sortKey)
 			if err != nil {
 				log.Fatal(err)
 			}
-		case <-done:
-			remaining = remaining - 1
-			log.Printf("One worker finished;  %v workers remaining.", remaining)
-			if remaining == 0 {
-				os.Exit(0)
-			}
 		}
+		writeTitles(fileName, sc.format, titles, sc.envelope)
 	}
+	skip := func(e *suger.FileError) error {
+		if sc.fatal {
+			return e
+		}
+		logger.Warn("skipping file", "error", e)
+		failures = append(failures, e)
+		return nil
+	}
+	scraper := &suger.Scraper{Workers: sc.workers, Glob: sc.glob, Recursive: sc.recursive, Logger: logger, Limit: sc.limit}
+	add := func(title *suger.Title) error {
+		if !dedup.Add(title) {
+			return nil
+		}
+		if len(title.Warnings) > 0 {
+			warned = warned + 1
+		}
+		if sc.onlyRefused && !title.Refused() {
+			return nil
+		}
+		if sc.keep != nil && !sc.keep(title) {
+			return nil
+		}
+		if stream != nil {
+			stream.Write(title)
+			return nil
+		}
+		titles = append(titles, title)
+		if sc.flushEvery > 0 && len(titles) >= sc.flushEvery {
+			chunk = chunk + 1
+			write(chunkName(sc.out, chunk, sc.format))
+			titles = nil
+		}
+		return nil
+	}
+	var err error
+	if sc.archive != "" {
+		err = scraper.ScrapeArchiveFunc(sc.archive, add, skip)
+	} else {
+		err = scraper.ScrapeDirFunc(sc.htmlDir, add, skip)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if dedup.Dropped > 0 {
+		logger.Info("dropped duplicate titles", "count", dedup.Dropped)
+	}
+	if warned > 0 {
+		logger.Warn("titles were scraped with warnings (e.g. no ratings)", "count", warned)
+	}
+	if len(failures) > 0 {
+		logger.Warn("files could not be scraped", "count", len(failures))
+		for _, e := range failures {
+			logger.Warn("could not scrape", "error", e)
+		}
+	}
+
+	//
+	// Output
+	//
+
+	if stream != nil {
+		stream.Close()
+		return
+	}
+
+	if sc.flushEvery > 0 {
+		// write the remainder, unless the last chunk took everything
+		if len(titles) > 0 || chunk == 0 {
+			chunk = chunk + 1
+			write(chunkName(sc.out, chunk, sc.format))
+		}
+		logger.Info("wrote chunk files", "count", chunk)
+		return
+	}
+	if sc.appendOut {
+		old, err := readTitlesFile(outFile(sc.format))
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		var replaced int
+		titles, replaced = suger.MergeTitles(old, titles)
+		logger.Info("appended titles", "existing", len(old), "replaced", replaced, "total", len(titles))
+	}
+	write(outFile(sc.format))
 }
 
-func scrapeCmd(htmlDir string, out string) {
+// fetchCmd() is called by the switch in main(). It fetches each title in refs, an ID or URL, with suger.Crawler.Fetch, and writes its HTML to htmlDir as title-ID.html, or if scrape is true, writes all the titles to standard output as a JSON array.
+func fetchCmd(ctx context.Context, refs []string, htmlDir string, scrape bool, opts []suger.CrawlerOption) error {
+	c, err := suger.NewCrawler(opts...)
+	if err != nil {
+		return err
+	}
+	if !scrape {
+		err = os.MkdirAll(htmlDir, dirPerm())
+		if err != nil {
+			return err
+		}
+	}
 	var titles []*suger.Title
-	files, err := ioutil.ReadDir(htmlDir)
-	for _, fileInfo := range files {
-		path := fmt.Sprintf("%s/%s", htmlDir, fileInfo.Name())
-		html, err := ioutil.ReadFile(path)
+	for _, ref := range refs {
+		r, err := c.Fetch(ctx, ref)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		title, err := suger.NewTitleFromHTML(html)
+		t, err := suger.NewTitleFromHTML(r.HTML)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		titles = append(titles, title)
+		logger.Info("fetched", "title", t)
+		if scrape {
+			titles = append(titles, t)
+			continue
+		}
+		if t.ID == "" {
+			msg := fmt.Sprintf("%s: no ID found in the title page's URL", ref)
+			return errors.New(msg)
+		}
+		path := filepath.Join(htmlDir, fmt.Sprintf("title-%s.html", t.ID))
+		err = atomicfile.WriteFile(path, r.HTML, filePerm)
+		if err != nil {
+			return err
+		}
+	}
+	if scrape {
+		writeJSON(stdoutName, titles, false)
 	}
+	return nil
+}
 
-	//
-	// JSON
-	//
+// deltaSkip returns the skip predicate for crawl -diff-against: it lists the search result grid over the crawl's range, start and count (zero meaning to the last result), with a Crawler made with opts, and passes over the titles already in the JSON file of titles known (see suger.DeltaSkip).
+func deltaSkip(ctx context.Context, known string, start int, count int, opts []suger.CrawlerOption) (func(int, int) bool, error) {
+	titles, err := readTitlesFile(known)
+	if err != nil {
+		return nil, err
+	}
+	c, err := suger.NewCrawler(opts...)
+	if err != nil {
+		return nil, err
+	}
+	first, _ := suger.ResultPosition(start, suger.ResultsPerPage)
+	last := 0
+	if count > 0 {
+		last, _ = suger.ResultPosition(start+count-1, suger.ResultsPerPage)
+	}
+	var entries []suger.ListEntry
+	err = c.ListTitles(ctx, first, last, func(e suger.ListEntry) error {
+		if e.Index >= start && (count == 0 || e.Index < start+count) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	skip, fetch := suger.DeltaSkip(entries, titles)
+	logger.Info("listed titles to crawl", "listed", len(entries), "known", len(entries)-fetch, "to_fetch", fetch)
+	return skip, nil
+}
 
-	fileName := fmt.Sprintf("%s/%s", out, "out.json")
-	f, err := os.Create(fileName)
+// listCmd() is called by the switch in main(). It lists the titles on the search result pages from first to last (see suger.Crawler.ListTitles), and writes them as a JSON array to out, or standard output if out is empty.
+func listCmd(ctx context.Context, first int, last int, out string, opts []suger.CrawlerOption) error {
+	c, err := suger.NewCrawler(opts...)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer f.Close()
-	w := bufio.NewWriter(f)
-	json, err := json.MarshalIndent(titles, "", "	")
-	_, err = w.Write(json)
+	var entries []suger.ListEntry
+	err = c.ListTitles(ctx, first, last, func(e suger.ListEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	err = w.Flush()
+	logger.Info("listed titles", "count", len(entries), "requests", c.Requests())
+	data, err := json.MarshalIndent(entries, "", "	")
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	data = append(data, '\n')
+	if out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return atomicfile.WriteFile(out, data, filePerm)
+}
+
+// diffCmd() is called by the switch in main(). It compares the titles in two JSON files written by scrape (see suger.DiffTitles), and writes the difference as JSON to out, or standard output if out is empty.
+func diffCmd(oldFile string, newFile string, out string) error {
+	before, err := readTitlesFile(oldFile)
+	if err != nil {
+		return err
+	}
+	after, err := readTitlesFile(newFile)
+	if err != nil {
+		return err
+	}
+	d := suger.DiffTitles(before, after)
+	logger.Info("diff", "added", len(d.Added), "removed", len(d.Removed), "modified", len(d.Modified))
+	data, err := json.MarshalIndent(d, "", "	")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
 	}
+	return atomicfile.WriteFile(out, data, filePerm)
+}
 
+// readTitlesFile reads the JSON array of titles in fileName (see suger.ReadTitles).
+func readTitlesFile(fileName string) ([]*suger.Title, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var titles []*suger.Title
+	err = suger.ReadTitles(f, func(t *suger.Title) error {
+		titles = append(titles, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return titles, nil
 }
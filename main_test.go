@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	suger "github.com/colinhb/suger/libsuger"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// only errors, as for -quiet
+	logger = newLogger(false, true)
+	os.Exit(m.Run())
+}
+
+// titlePage returns a title page, as the site serves it, for a title with the given database ID, name and ratings. A Rating with an empty Rating is given as text (e.g. for a refusal), any other as an image.
+func titlePage(id string, name string, ratings ...suger.Rating) string {
+	var rows strings.Builder
+	for _, r := range ratings {
+		cell := "-"
+		if r.Rating != "" {
+			cell = fmt.Sprintf(`<img src="Rating.png" alt="%s" />`, r.Rating)
+		}
+		fmt.Fprintf(&rows, "<tr><td>DVD</td><td>N/A</td><td>%s</td><td>%s</td><td>90</td><td>N/A</td></tr>\n", cell, r.Decision)
+	}
+	return fmt.Sprintf(`<html><body><form method="post" action="SearchDetail.aspx?sType=Feature&amp;sRowID=%s" id="form1">
+<div id="content"><span id="lblTitle">%s</span>
+<table>
+<tr><td><b>Format</b></td><td><b>Region</b></td><td><b>Rating</b></td><td><b>Decision</b></td><td><b>Duration</b></td><td><b>Distributor</b></td></tr>
+%s</table></div></form></body></html>`, id, name, rows.String())
+}
+
+// writeFiles writes each of files, keyed by its path relative to dir, creating directories as need be.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readNames returns the names of the titles in the JSON file of titles at path, in order.
+func readNames(t *testing.T, path string) []string {
+	t.Helper()
+	titles, err := readTitlesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, title := range titles {
+		names = append(names, title.Name)
+	}
+	return names
+}
+
+// equalStrings returns true if a and b hold the same strings in the same order.
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestScrapeOnlyRefused(t *testing.T) {
+	dir := t.TempDir()
+	html := filepath.Join(dir, "html")
+	writeFiles(t, html, map[string]string{
+		"title-1-0.html": titlePage("ID1", "PASSED", suger.Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}),
+		"title-1-1.html": titlePage("ID2", "BANNED", suger.Rating{Decision: "Banned"}),
+		"title-1-2.html": titlePage("ID3", "CUT", suger.Rating{Rating: "Restricted 21", Decision: "Passed With Cuts"}),
+		"title-1-3.html": titlePage("ID4", "NAR", suger.Rating{Decision: "Not for All Ratings"}),
+		"title-1-4.html": titlePage("ID5", "PASSED THEN REFUSED",
+			suger.Rating{Rating: "Matured Above 18", Decision: "Passed Clean"},
+			suger.Rating{Decision: "Not for All Ratings"}),
+	})
+	out := filepath.Join(dir, "out")
+	scrapeCmd(scrapeConfig{htmlDir: html, out: out, format: "json", sortKey: "name", onlyRefused: true})
+	got := readNames(t, filepath.Join(out, "out.json"))
+	want := []string{"BANNED", "NAR", "PASSED THEN REFUSED"}
+	if !equalStrings(got, want) {
+		t.Errorf("got titles %q, want %q", got, want)
+	}
+
+	// composed with a rating filter, both must keep a title
+	keep, err := ratingFilter("Matured Above 18", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrapeCmd(scrapeConfig{htmlDir: html, out: out, format: "json", sortKey: "name", onlyRefused: true, keep: keep})
+	got = readNames(t, filepath.Join(out, "out.json"))
+	want = []string{"PASSED THEN REFUSED"}
+	if !equalStrings(got, want) {
+		t.Errorf("with -rating, got titles %q, want %q", got, want)
+	}
+}
+
+// writeTitlePages writes n title pages, named title-1-ROW.html, to dir, for the titles "TITLE 1" to "TITLE n".
+func writeTitlePages(t *testing.T, dir string, n int) {
+	t.Helper()
+	files := make(map[string]string)
+	for i := 1; i <= n; i++ {
+		name := fmt.Sprintf("title-1-%v.html", i-1)
+		files[name] = titlePage(fmt.Sprintf("ID%v", i), fmt.Sprintf("TITLE %v", i), suger.Rating{Rating: "Parental Guidance", Decision: "Passed Clean"})
+	}
+	writeFiles(t, dir, files)
+}
+
+func TestScrapeFlushEvery(t *testing.T) {
+	tests := []struct {
+		titles int
+		every  int
+		chunks []int // titles in each chunk
+	}{
+		{5, 2, []int{2, 2, 1}},
+		{4, 2, []int{2, 2}},
+		{3, 5, []int{3}},
+		{1, 1, []int{1}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		html := filepath.Join(dir, "html")
+		writeTitlePages(t, html, tt.titles)
+		out := filepath.Join(dir, "out")
+		scrapeCmd(scrapeConfig{htmlDir: html, out: out, format: "json", sortKey: "none", flushEvery: tt.every})
+		total := 0
+		for i, n := range tt.chunks {
+			names := readNames(t, chunkName(out, i+1, "json"))
+			if len(names) != n {
+				t.Errorf("%v titles, every %v: chunk %v has %v titles, want %v", tt.titles, tt.every, i+1, len(names), n)
+			}
+			total = total + len(names)
+		}
+		if total != tt.titles {
+			t.Errorf("%v titles, every %v: chunks hold %v titles", tt.titles, tt.every, total)
+		}
+		_, err := os.Stat(chunkName(out, len(tt.chunks)+1, "json"))
+		if !os.IsNotExist(err) {
+			t.Errorf("%v titles, every %v: wrote more than %v chunks", tt.titles, tt.every, len(tt.chunks))
+		}
+		_, err = os.Stat(filepath.Join(out, "out.json"))
+		if !os.IsNotExist(err) {
+			t.Errorf("%v titles, every %v: wrote out.json as well as chunks", tt.titles, tt.every)
+		}
+	}
+}
+
+func TestScrapeAppend(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	first := filepath.Join(dir, "first")
+	writeFiles(t, first, map[string]string{
+		"title-1-0.html": titlePage("ID1", "KEPT", suger.Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}),
+		"title-1-1.html": titlePage("ID2", "OLD NAME", suger.Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}),
+	})
+	// appending to no out.json writes a new one
+	scrapeCmd(scrapeConfig{htmlDir: first, out: out, format: "json", sortKey: "none", appendOut: true})
+	got := readNames(t, filepath.Join(out, "out.json"))
+	want := []string{"KEPT", "OLD NAME"}
+	if !equalStrings(got, want) {
+		t.Errorf("first scrape: got titles %q, want %q", got, want)
+	}
+
+	second := filepath.Join(dir, "second")
+	writeFiles(t, second, map[string]string{
+		"title-2-0.html": titlePage("ID2", "NEW NAME", suger.Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}),
+		"title-2-1.html": titlePage("ID3", "ADDED", suger.Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}),
+	})
+	scrapeCmd(scrapeConfig{htmlDir: second, out: out, format: "json", sortKey: "none", appendOut: true})
+	// the newer ID2 replaces the old in its place, and ID3 follows
+	got = readNames(t, filepath.Join(out, "out.json"))
+	want = []string{"KEPT", "NEW NAME", "ADDED"}
+	if !equalStrings(got, want) {
+		t.Errorf("appended: got titles %q, want %q", got, want)
+	}
+
+	// without -append, out.json is overwritten
+	scrapeCmd(scrapeConfig{htmlDir: second, out: out, format: "json", sortKey: "none"})
+	got = readNames(t, filepath.Join(out, "out.json"))
+	want = []string{"NEW NAME", "ADDED"}
+	if !equalStrings(got, want) {
+		t.Errorf("overwritten: got titles %q, want %q", got, want)
+	}
+}
+
+func TestScrapeLimit(t *testing.T) {
+	dir := t.TempDir()
+	html := filepath.Join(dir, "html")
+	writeTitlePages(t, html, 10)
+	for _, sortKey := range []string{"none", "name"} {
+		out := filepath.Join(dir, "out-"+sortKey)
+		scrapeCmd(scrapeConfig{htmlDir: html, out: out, format: "json", sortKey: sortKey, limit: 3, workers: 4})
+		got := readNames(t, filepath.Join(out, "out.json"))
+		want := []string{"TITLE 1", "TITLE 2", "TITLE 3"}
+		if !equalStrings(got, want) {
+			t.Errorf("sort %v: got titles %q, want %q", sortKey, got, want)
+		}
+	}
+}
+
+func TestHeredoc(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"too short", "\n", ""},
+		{"one line", "\n\t\tone\n", "one"},
+		{"mixed indentation", "\n\t\tfirst\n\t\t\tindented\n\tleast\n\t\tlast\n", "\tfirst\n\t\tindented\nleast\n\tlast"},
+		{"least indented later", "\n\t\t\tdeep\n\t\tshallow\n", "\tdeep\nshallow"},
+		{"blank interior lines", "\n\t\tfirst\n\n\t\t\n\t\tlast\n", "first\n\n\nlast"},
+		{"blank first line", "\n\n\t\tonly\n", "\nonly"},
+		{"no indentation", "\nfirst\n\tsecond\n", "first\n\tsecond"},
+		{"spaces don't count", "\n    spaced\n\t\ttabbed\n", "    spaced\ntabbed"},
+	}
+	for _, tt := range tests {
+		if got := Heredoc(tt.doc); got != tt.want {
+			t.Errorf("%s: Heredoc(%q) = %q, want %q", tt.name, tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestParseNameTemplate(t *testing.T) {
+	for _, text := range []string{defaultNameTemplate, "{{.Index}}.html", "{{.Page}}/{{.Row}}.html.gz"} {
+		_, err := parseNameTemplate(text)
+		if err != nil {
+			t.Errorf("parseNameTemplate(%q): %v", text, err)
+		}
+	}
+	for _, text := range []string{"", "{{.Page", "{{.Title}}.html", "{{if .URL}}{{end}}"} {
+		_, err := parseNameTemplate(text)
+		if err == nil {
+			t.Errorf("parseNameTemplate(%q) succeeded", text)
+		}
+	}
+	tmpl, err := parseNameTemplate("{{.Index}}.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := resultFile("html", tmpl, suger.Result{Page: 2, Row: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("html", "25.html"); file != want {
+		t.Errorf("resultFile gave %q, want %q", file, want)
+	}
+}
+
+func TestDiffCmd(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"old.json": `[{"ID": "A", "Name": "A"}, {"ID": "B", "Name": "B"}]`,
+		"new.json": `[{"ID": "B", "Name": "B", "Ratings": [{"Rating": "Parental Guidance", "Decision": "Passed Clean"}]}, {"ID": "C", "Name": "C"}]`,
+	})
+	out := filepath.Join(dir, "diff.json")
+	err := diffCmd(filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json"), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d suger.TitleDiff
+	err = json.Unmarshal(data, &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Added) != 1 || d.Added[0].ID != "C" || len(d.Removed) != 1 || d.Removed[0].ID != "A" || len(d.Modified) != 1 || d.Modified[0].Key != "B" {
+		t.Errorf("diff %s, want C added, A removed and B modified", data)
+	}
+}
+
+func TestRatingFilter(t *testing.T) {
+	titles := []*suger.Title{
+		{Name: "R21", Ratings: []suger.Rating{{Rating: "Restricted 21"}}},
+		{Name: "NC16", Ratings: []suger.Rating{{Rating: "No Children Under 16"}}},
+		{Name: "PG", Ratings: []suger.Rating{{Rating: "Parental Guidance"}}},
+		{Name: "UNRATED"},
+	}
+	tests := []struct {
+		rating    string
+		minRating string
+		want      []string
+	}{
+		{"No Children Under 16", "", []string{"NC16"}},
+		{"", "No Children Under 16", []string{"R21", "NC16"}},
+		{"Parental Guidance", "No Children Under 16", nil},
+		{"none", "", []string{"UNRATED"}},
+	}
+	for _, tt := range tests {
+		keep, err := ratingFilter(tt.rating, tt.minRating)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, title := range titles {
+			if keep(title) {
+				got = append(got, title.Name)
+			}
+		}
+		if !equalStrings(got, tt.want) {
+			t.Errorf("-rating %q -min-rating %q kept %q, want %q", tt.rating, tt.minRating, got, tt.want)
+		}
+	}
+	keep, err := ratingFilter("", "")
+	if keep != nil || err != nil {
+		t.Errorf("no flags gave a filter")
+	}
+	_, err = ratingFilter("R21", "")
+	if err == nil {
+		t.Errorf("an unknown rating was accepted")
+	}
+}
+
+// captureLog makes logger write to the returned buffer, at every level, until the test ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	old := logger
+	logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	t.Cleanup(func() { logger = old })
+	return &buf
+}
+
+func TestDirStoreCollision(t *testing.T) {
+	logged := captureLog(t)
+	old := written
+	written = &writtenFiles{sums: make(map[string][sha256.Size]byte)}
+	t.Cleanup(func() { written = old })
+
+	dir := t.TempDir()
+	store := dirStore(dir, nil, false)
+	// overlapping partitions: two workers both crawl page 1, row 3,
+	// one of them after the site changed the page
+	first := suger.Result{Page: 1, Row: 3, HTML: []byte("first")}
+	for _, r := range []suger.Result{first, first} {
+		err := store.Store(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Contains(logged.String(), "same file name") {
+		t.Errorf("the same result stored twice was reported as a collision")
+	}
+	err := store.Store(suger.Result{Page: 1, Row: 3, HTML: []byte("second")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logged.String(), "same file name") || !strings.Contains(logged.String(), "title-1-3.html") {
+		t.Errorf("collision wasn't reported; logged %q", logged.String())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "title-1-3.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first" {
+		t.Errorf("the file holds %q, want the first result's", data)
+	}
+}
+
+func TestCapWorkers(t *testing.T) {
+	tests := []struct {
+		workers int
+		count   int
+		want    int
+		warned  bool
+	}{
+		{4, 10, 4, false},
+		{10, 10, 10, false},
+		{50, 10, 10, true},
+		{2, 1, 1, true},
+		{8, 0, 8, false}, // no count to cap at
+	}
+	for _, tt := range tests {
+		logged := captureLog(t)
+		got, err := capWorkers(tt.workers, tt.count)
+		if err != nil {
+			t.Errorf("%v workers for %v results: %v", tt.workers, tt.count, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v workers for %v results: got %v, want %v", tt.workers, tt.count, got, tt.want)
+		}
+		if warned := strings.Contains(logged.String(), "more workers than results"); warned != tt.warned {
+			t.Errorf("%v workers for %v results: warned %v, want %v", tt.workers, tt.count, warned, tt.warned)
+		}
+	}
+	for _, workers := range []int{0, -1} {
+		_, err := capWorkers(workers, 10)
+		if err == nil {
+			t.Errorf("%v workers were accepted", workers)
+		}
+	}
+}
+
+func TestCheckCmd(t *testing.T) {
+	logged := captureLog(t)
+	dir := t.TempDir()
+	form := `<html><body><form method="post" action="./" id="form1"><input type="hidden" name="__VIEWSTATE" id="__VIEWSTATE" value="" />%s</form></body></html>`
+	writeFiles(t, dir, map[string]string{
+		"title-1-0.html": titlePage("ID1", "VALID", suger.Rating{Rating: "Parental Guidance", Decision: "Passed Clean"}),
+		"title-1-1.html": titlePage("ID2", "ALSO VALID", suger.Rating{Rating: "General Viewing", Decision: "Passed Clean"}),
+		"title-1-2.html": "",
+		"title-1-3.html": fmt.Sprintf(form, `<table id="gvResult"><tr><td>A TITLE</td></tr></table>`),
+		"title-1-4.html": fmt.Sprintf(form, `<input type="submit" name="btnSearch" id="btnSearch" value="Search" />`),
+		"title-1-5.html": "<html><body><h1>Access Denied</h1></body></html>",
+		"title-1-6.html": titlePage("ID7", "NO RATINGS"),
+		"notes.txt":      "not a page",
+	})
+	var bad int
+	var err error
+	out := captureStdout(t, func() {
+		bad, err = checkCmd(&suger.Scraper{Strict: true, Workers: 2}, dir, "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bad != 5 {
+		t.Errorf("%v bad files, want 5", bad)
+	}
+	// each bad file is listed, and no other
+	for _, name := range []string{"title-1-2.html", "title-1-3.html", "title-1-4.html", "title-1-5.html", "title-1-6.html"} {
+		if !bytes.Contains(out, []byte(name)) {
+			t.Errorf("%v wasn't listed; printed %q", name, out)
+		}
+	}
+	for _, name := range []string{"title-1-0.html", "title-1-1.html", "notes.txt"} {
+		if bytes.Contains(out, []byte(name)) {
+			t.Errorf("%v was listed; printed %q", name, out)
+		}
+	}
+	for _, count := range []string{"valid=2", "empty=1", "search_results=1", "search_forms=1", "blocked=1", "other=1"} {
+		if !strings.Contains(logged.String(), count) {
+			t.Errorf("didn't log %v; logged %q", count, logged.String())
+		}
+	}
+
+	// a directory of good pages has none bad
+	good := t.TempDir()
+	writeTitlePages(t, good, 3)
+	captureStdout(t, func() {
+		bad, err = checkCmd(&suger.Scraper{Strict: true}, good, "")
+	})
+	if err != nil || bad != 0 {
+		t.Errorf("all good: %v bad files (%v), want none", bad, err)
+	}
+}
+
+// resumeSite is an http.Handler serving a single page of search results, as the site does, for the crawl in TestResume; it records the row of each title asked for.
+type resumeSite struct {
+	total int
+
+	mu   sync.Mutex
+	rows []int
+}
+
+func (s *resumeSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	magic := `<input type="hidden" name="__VIEWSTATE" id="__VIEWSTATE" value="page-1" />
+<input type="hidden" name="__VIEWSTATEGENERATOR" id="__VIEWSTATEGENERATOR" value="808F470E" />
+<input type="hidden" name="__EVENTVALIDATION" id="__EVENTVALIDATION" value="valid" />`
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	r.ParseForm()
+	event := r.PostForm.Get("__EVENTARGUMENT")
+	switch {
+	case r.Method == "GET":
+		fmt.Fprintf(w, `<html><body><form method="post" action="./" id="form1">%s
+<input type="text" name="txtTitle" id="txtTitle" />
+<input type="submit" name="btnSearch" id="btnSearch" value="Search" />
+</form></body></html>`, magic)
+	case r.PostForm.Get("btnSearch") != "":
+		var b strings.Builder
+		fmt.Fprintf(&b, `<html><body><form method="post" action="./" id="form1">%s`, magic)
+		fmt.Fprintf(&b, `<span id="lblCount">%v records found</span><table id="gvResult">`, s.total)
+		for row := 0; row < s.total; row++ {
+			fmt.Fprintf(&b, `<tr><td><a href="javascript:__doPostBack('gvResult','Title$%v')">TITLE %v</a></td></tr>`, row, row+1)
+		}
+		b.WriteString(`</table></form></body></html>`)
+		fmt.Fprint(w, b.String())
+	case strings.HasPrefix(event, "Title$"):
+		row, _ := strconv.Atoi(strings.TrimPrefix(event, "Title$"))
+		s.mu.Lock()
+		s.rows = append(s.rows, row)
+		s.mu.Unlock()
+		fmt.Fprint(w, titlePage(fmt.Sprintf("ID%v", row+1), fmt.Sprintf("TITLE %v", row+1)))
+	default:
+		http.Error(w, "unknown postback", http.StatusBadRequest)
+	}
+}
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	tmpl, err := parseNameTemplate("{{.Page}}-{{.Row}}.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"1-0.html":    titlePage("ID1", "TITLE 1"),
+		"1-2.html.gz": "gzipped",
+		"1-4.html":    titlePage("ID5", "TITLE 5"),
+		"2-1.html.gz": "gzipped",
+		"1-5.txt":     "not a result",
+	})
+	existing := existingResults(dir, tmpl)
+	tests := []struct {
+		page int
+		row  int
+		want bool
+	}{
+		{1, 0, true},
+		{1, 1, false},
+		{1, 2, true},
+		{1, 3, false},
+		{1, 4, true},
+		{1, 5, false},
+		{2, 0, false},
+		{2, 1, true},
+	}
+	for _, tt := range tests {
+		if got := existing(tt.page, tt.row); got != tt.want {
+			t.Errorf("page %v, row %v: got %v, want %v", tt.page, tt.row, got, tt.want)
+		}
+	}
+
+	// the crawl, as -resume runs it, fetches only the rows not already in dir
+	site := &resumeSite{total: 6}
+	srv := httptest.NewServer(site)
+	defer srv.Close()
+	opts := []suger.CrawlerOption{suger.WithBaseURL(srv.URL), suger.WithSkip(anyOf([]func(int, int) bool{existing}))}
+	err = crawlCmd(context.Background(), 1, 6, 1, dirStore(dir, tmpl, false), nil, "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	site.mu.Lock()
+	rows := site.rows
+	site.mu.Unlock()
+	want := map[int]bool{1: true, 3: true, 5: true}
+	if len(rows) != len(want) {
+		t.Errorf("fetched rows %v, want %v", rows, want)
+	}
+	for _, row := range rows {
+		if !want[row] {
+			t.Errorf("row %v, already in %v, was fetched again", row, dir)
+		}
+	}
+	for row := range want {
+		_, err := os.Stat(filepath.Join(dir, fmt.Sprintf("1-%v.html", row)))
+		if err != nil {
+			t.Errorf("row %v: %v", row, err)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/colinhb/suger/internal/atomicfile"
+	suger "github.com/colinhb/suger/libsuger"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version is suger's version, recorded in the envelope of scrape -envelope. It is set at build time with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// envelope is the JSON object scrape -envelope writes, recording the version of suger that wrote the titles and when, rather than a bare array of titles.
+type envelope struct {
+	SugerVersion string         `json:"suger_version"`
+	CrawledAt    time.Time      `json:"crawled_at"`
+	Count        int            `json:"count"`
+	Titles       []*suger.Title `json:"titles"`
+}
+
+// filePerm is the permission of the files suger writes (see -perm). Directories it creates get the same permission, plus search wherever it allows reading.
+var filePerm os.FileMode = 0644
+
+// dirPerm returns the permission of the directories suger creates (see filePerm).
+func dirPerm() os.FileMode {
+	return filePerm | (filePerm&0444)>>2
+}
+
+// permFlag is a flag.Value for an octal permission such as 0644.
+type permFlag struct {
+	perm *os.FileMode
+}
+
+func (pf permFlag) String() string {
+	if pf.perm == nil {
+		return ""
+	}
+	return fmt.Sprintf("%#o", *pf.perm)
+}
+
+func (pf permFlag) Set(s string) error {
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 0777 {
+		return fmt.Errorf("%q is not an octal permission (e.g. 0644)", s)
+	}
+	*pf.perm = os.FileMode(n)
+	return nil
+}
+
+// stdoutName is the -out of scrape that writes to standard output rather than a file.
+const stdoutName = "-"
+
+// createFile starts writing fileName with permission filePerm, or returns os.Stdout if fileName is stdoutName. The file is written under a temporary name and only replaces fileName when it is closed (see atomicfile.Create), so a scrape that fails part way never leaves a truncated output file.
+func createFile(fileName string) (io.WriteCloser, error) {
+	if fileName == stdoutName {
+		return os.Stdout, nil
+	}
+	return atomicfile.Create(fileName, filePerm)
+}
+
+// validFormat reports whether format is an output format supported by scrape.
+func validFormat(format string) bool {
+	switch format {
+	case "json", "csv", "ndjson", "sqlite":
+		return true
+	}
+	return false
+}
+
+// validSortKey reports whether key is one of suger.SortKeys, or "none" (the order the files are scraped in).
+func validSortKey(key string) bool {
+	if key == "none" {
+		return true
+	}
+	for _, k := range suger.SortKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// chunkName returns the name of the nth chunk file written by scrape -flush-every.
+func chunkName(out string, n int, format string) string {
+	return fmt.Sprintf("%s/out-%04d.%s", out, n, format)
+}
+
+// writeTitles writes titles to fileName in the given format, json in an envelope if wrap is true.
+func writeTitles(fileName string, format string, titles []*suger.Title, wrap bool) {
+	switch format {
+	case "csv":
+		writeCSV(fileName, titles)
+	default:
+		writeJSON(fileName, titles, wrap)
+	}
+}
+
+// writeJSON writes titles to fileName as an indented JSON array, or if wrap is true, as the titles of an envelope. A file is replaced atomically, so that an existing one (e.g. that -append is adding to) is never left partly written.
+func writeJSON(fileName string, titles []*suger.Title, wrap bool) {
+	var v interface{} = titles
+	if wrap {
+		if titles == nil {
+			titles = []*suger.Title{}
+		}
+		v = envelope{SugerVersion: version, CrawledAt: time.Now(), Count: len(titles), Titles: titles}
+	}
+	json, err := json.MarshalIndent(v, "", "	")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if fileName == stdoutName {
+		_, err = os.Stdout.Write(json)
+	} else {
+		err = atomicfile.WriteFile(fileName, json, filePerm)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// titleStream is an output that titles are written to one by one as they are scraped (see -format), rather than collected and written at the end.
+type titleStream interface {
+	Write(t *suger.Title)
+	Close()
+}
+
+// jsonArrayWriter writes titles to a file as they are scraped, as a JSON array formatted like writeJSON's, so that they needn't be held in memory. In an envelope, the count is written after the titles, once it is known.
+type jsonArrayWriter struct {
+	f      io.WriteCloser
+	w      *bufio.Writer
+	n      int    // titles written
+	indent string // of the array's elements
+	wrap   bool   // in an envelope
+}
+
+func newJSONArrayWriter(fileName string, wrap bool) *jsonArrayWriter {
+	f, err := createFile(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	jw := &jsonArrayWriter{f: f, w: w, indent: "	", wrap: wrap}
+	if wrap {
+		jw.indent = "		"
+		crawledAt, err := time.Now().MarshalJSON()
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = fmt.Fprintf(w, "{\n	\"suger_version\": %q,\n	\"crawled_at\": %s,\n	\"titles\": ", version, crawledAt)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	_, err = w.WriteString("[")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return jw
+}
+
+func (jw *jsonArrayWriter) Write(t *suger.Title) {
+	data, err := json.MarshalIndent(t, jw.indent, "	")
+	if err != nil {
+		log.Fatal(err)
+	}
+	sep := ",\n" + jw.indent
+	if jw.n == 0 {
+		sep = "\n" + jw.indent
+	}
+	_, err = jw.w.WriteString(sep)
+	if err == nil {
+		_, err = jw.w.Write(data)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	jw.n = jw.n + 1
+}
+
+func (jw *jsonArrayWriter) Close() {
+	end := "\n" + jw.indent[1:] + "]"
+	if jw.n == 0 {
+		end = "]"
+	}
+	if jw.wrap {
+		end = end + fmt.Sprintf(",\n	\"count\": %d\n}", jw.n)
+	}
+	_, err := jw.w.WriteString(end)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = jw.w.Flush()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = jw.f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ndjsonWriter writes titles to a file as they are scraped, one JSON object per line, so that they needn't be held in memory.
+type ndjsonWriter struct {
+	f   io.WriteCloser
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(fileName string) *ndjsonWriter {
+	f, err := createFile(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	return &ndjsonWriter{f: f, w: w, enc: json.NewEncoder(w)}
+}
+
+func (nw *ndjsonWriter) Write(t *suger.Title) {
+	// Encode terminates each value with a newline
+	err := nw.enc.Encode(t)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (nw *ndjsonWriter) Close() {
+	err := nw.w.Flush()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = nw.f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// csvHeader is the header row of CSV output. A title's ratings are joined into a single cell, as "rating: decision" pairs separated by "; ", so there is exactly one row per title.
+var csvHeader = []string{"Name", "URL", "MaxRating", "Ratings (rating: decision; ...)"}
+
+// writeCSV writes titles to fileName as CSV, one row per title (see csvHeader).
+func writeCSV(fileName string, titles []*suger.Title) {
+	f, err := createFile(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := csv.NewWriter(f)
+	err = w.Write(csvHeader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, t := range titles {
+		err = w.Write(csvRecord(t))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	w.Flush()
+	err = w.Error()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func csvRecord(t *suger.Title) []string {
+	max, ok := t.MaxRating()
+	if !ok {
+		max = ""
+	}
+	var ratings []string
+	for _, r := range t.Ratings {
+		ratings = append(ratings, fmt.Sprintf("%s: %s", r.Rating, r.Decision))
+	}
+	return []string{t.Name, t.URL, max, strings.Join(ratings, "; ")}
+}
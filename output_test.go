@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	suger "github.com/colinhb/suger/libsuger"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleTitles returns n titles, "TITLE 1" to "TITLE n", each with a rating.
+func sampleTitles(n int) []*suger.Title {
+	var titles []*suger.Title
+	for i := 1; i <= n; i++ {
+		titles = append(titles, &suger.Title{
+			ID:      fmt.Sprintf("ID%v", i),
+			Name:    fmt.Sprintf("TITLE %v", i),
+			Ratings: []suger.Rating{{Rating: "Parental Guidance", Decision: "Passed Clean"}},
+		})
+	}
+	return titles
+}
+
+func TestJSONArrayWriterMatchesWriteJSON(t *testing.T) {
+	for _, n := range []int{0, 1, 3} {
+		dir := t.TempDir()
+		titles := sampleTitles(n)
+		streamed, whole := filepath.Join(dir, "streamed.json"), filepath.Join(dir, "whole.json")
+		jw := newJSONArrayWriter(streamed, false)
+		for _, title := range titles {
+			jw.Write(title)
+		}
+		jw.Close()
+		writeJSON(whole, titles, false)
+		a, err := os.ReadFile(streamed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(whole)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// writeJSON writes a nil slice as null
+		if n > 0 && !bytes.Equal(a, b) {
+			t.Errorf("%v titles: streamed\n%s\nwant\n%s", n, a, b)
+		}
+
+		// in an envelope, only the time may differ
+		jw = newJSONArrayWriter(streamed, true)
+		for _, title := range titles {
+			jw.Write(title)
+		}
+		jw.Close()
+		writeJSON(whole, titles, true)
+		var ea, eb envelope
+		for _, f := range []struct {
+			name string
+			e    *envelope
+		}{{streamed, &ea}, {whole, &eb}} {
+			data, err := os.ReadFile(f.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = json.Unmarshal(data, f.e)
+			if err != nil {
+				t.Fatalf("%v titles: %v: %v", n, f.name, err)
+			}
+		}
+		ea.CrawledAt, eb.CrawledAt = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(ea, eb) || ea.Count != n || len(ea.Titles) != n {
+			t.Errorf("%v titles: streamed envelope %+v, want %+v", n, ea, eb)
+		}
+	}
+}
+
+func TestScrapeEnvelope(t *testing.T) {
+	defer func(v string) { version = v }(version)
+	version = "v1.2.3"
+	dir := t.TempDir()
+	html := filepath.Join(dir, "html")
+	writeTitlePages(t, html, 3)
+	// streamed when unsorted, and written whole when sorted
+	for _, sortKey := range []string{"none", "name"} {
+		out := filepath.Join(dir, "out-"+sortKey)
+		before := time.Now()
+		scrapeCmd(scrapeConfig{htmlDir: html, out: out, format: "json", sortKey: sortKey, envelope: true})
+		after := time.Now()
+		data, err := os.ReadFile(filepath.Join(out, "out.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(data, &fields)
+		if err != nil {
+			t.Fatalf("sort %v: %v", sortKey, err)
+		}
+		for _, name := range []string{"suger_version", "crawled_at", "count", "titles"} {
+			if _, ok := fields[name]; !ok {
+				t.Errorf("sort %v: envelope has no %v", sortKey, name)
+			}
+		}
+		var e envelope
+		err = json.Unmarshal(data, &e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.SugerVersion != "v1.2.3" || e.Count != 3 {
+			t.Errorf("sort %v: suger_version %q, count %v, want v1.2.3, 3", sortKey, e.SugerVersion, e.Count)
+		}
+		if e.CrawledAt.Before(before.Truncate(time.Second)) || e.CrawledAt.After(after) {
+			t.Errorf("sort %v: crawled_at %v, not during the scrape", sortKey, e.CrawledAt)
+		}
+		var names []string
+		for _, title := range e.Titles {
+			names = append(names, title.Name)
+		}
+		// readNames reads the titles of an envelope as of an array
+		want := []string{"TITLE 1", "TITLE 2", "TITLE 3"}
+		if !equalStrings(names, want) || !equalStrings(readNames(t, filepath.Join(out, "out.json")), want) {
+			t.Errorf("sort %v: titles %q, want %q", sortKey, names, want)
+		}
+	}
+}
+
+// peakHeap runs fn, and returns the most heap memory in use, sampled every millisecond, while it ran.
+func peakHeap(fn func()) uint64 {
+	done := make(chan struct{})
+	peak := make(chan uint64)
+	go func() {
+		var max uint64
+		var ms runtime.MemStats
+		tick := time.NewTicker(time.Millisecond)
+		defer tick.Stop()
+		for {
+			runtime.ReadMemStats(&ms)
+			if ms.HeapInuse > max {
+				max = ms.HeapInuse
+			}
+			select {
+			case <-done:
+				peak <- max
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+	fn()
+	close(done)
+	return <-peak
+}
+
+// BenchmarkScrapeJSON scrapes a directory of title pages to out.json, streamed as they are scraped (-sort none) or collected and sorted first (-sort name), reporting the peak heap in use: streaming should hold about one title at a time, however many pages there are.
+func BenchmarkScrapeJSON(b *testing.B) {
+	dir := b.TempDir()
+	html := filepath.Join(dir, "html")
+	err := os.MkdirAll(html, 0755)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 1; i <= 5000; i++ {
+		page := titlePage(fmt.Sprintf("ID%v", i), fmt.Sprintf("TITLE %v", i), suger.Rating{Rating: "Parental Guidance", Decision: "Passed Clean"})
+		err = os.WriteFile(filepath.Join(html, fmt.Sprintf("title-%v.html", i)), []byte(page), 0644)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, sortKey := range []string{"none", "name"} {
+		b.Run("sort="+sortKey, func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				p := peakHeap(func() {
+					scrapeCmd(scrapeConfig{htmlDir: html, out: filepath.Join(dir, "out"), format: "json", sortKey: sortKey})
+				})
+				if p > peak {
+					peak = p
+				}
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+		})
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+	fn()
+	os.Stdout = old
+	w.Close() // the output may already have closed it
+	return <-out
+}
+
+func TestScrapeToStdout(t *testing.T) {
+	html := t.TempDir()
+	writeTitlePages(t, html, 3)
+	tests := []struct {
+		format  string
+		sortKey string
+		check   func(data []byte) error
+	}{
+		{"json", "name", func(data []byte) error {
+			var titles []*suger.Title
+			err := json.Unmarshal(data, &titles)
+			if err == nil && len(titles) != 3 {
+				err = fmt.Errorf("%v titles", len(titles))
+			}
+			return err
+		}},
+		{"json", "none", func(data []byte) error { // streamed
+			var titles []*suger.Title
+			err := json.Unmarshal(data, &titles)
+			if err == nil && len(titles) != 3 {
+				err = fmt.Errorf("%v titles", len(titles))
+			}
+			return err
+		}},
+		{"ndjson", "none", func(data []byte) error {
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if len(lines) != 3 {
+				return fmt.Errorf("%v lines", len(lines))
+			}
+			for _, line := range lines {
+				var title suger.Title
+				err := json.Unmarshal([]byte(line), &title)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"csv", "name", func(data []byte) error {
+			records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+			if err == nil && len(records) != 4 {
+				err = fmt.Errorf("%v records, with the header", len(records))
+			}
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		data := captureStdout(t, func() {
+			scrapeCmd(scrapeConfig{htmlDir: html, out: stdoutName, format: tt.format, sortKey: tt.sortKey})
+		})
+		err := tt.check(data)
+		if err != nil {
+			t.Errorf("-format %v -sort %v: %v; wrote %q", tt.format, tt.sortKey, err, data)
+		}
+		if !bytes.Contains(data, []byte("TITLE 2")) {
+			t.Errorf("-format %v -sort %v: wrote %q", tt.format, tt.sortKey, data)
+		}
+	}
+	if _, err := os.Stat(stdoutName); !os.IsNotExist(err) {
+		t.Errorf("wrote a file named %q", stdoutName)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	suger "github.com/colinhb/suger/libsuger"
+	"log"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the tables written by scrape -format sqlite: one row per title in titles, keyed by suger.Title.Key, and one row per rating in ratings.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS titles (
+	id           TEXT PRIMARY KEY,
+	name         TEXT NOT NULL,
+	url          TEXT NOT NULL,
+	distributor  TEXT NOT NULL,
+	running_time INTEGER NOT NULL,
+	max_rating   TEXT
+);
+CREATE TABLE IF NOT EXISTS ratings (
+	title_id TEXT NOT NULL REFERENCES titles(id) ON DELETE CASCADE,
+	rating   TEXT NOT NULL,
+	decision TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ratings_title_id ON ratings(title_id);
+`
+
+// sqliteUpsert inserts a title, or updates it if a title with the same id was written before (e.g. by an earlier scrape).
+const sqliteUpsert = `
+INSERT INTO titles (id, name, url, distributor, running_time, max_rating)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+	name = excluded.name,
+	url = excluded.url,
+	distributor = excluded.distributor,
+	running_time = excluded.running_time,
+	max_rating = excluded.max_rating`
+
+// sqliteWriter writes titles to a SQLite database as they are scraped, in a single transaction committed by Close. Like ndjsonWriter, it needn't hold the titles in memory.
+type sqliteWriter struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func newSQLiteWriter(fileName string) *sqliteWriter {
+	// foreign_keys is a setting of each connection, not of the database, so it is set by the DSN for every connection the pool opens
+	db, err := sql.Open("sqlite", fileName+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the writer uses one connection at a time anyway; keeping to one also keeps an in-memory database from being a different one per connection
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(sqliteSchema)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &sqliteWriter{db: db, tx: tx}
+}
+
+func (sw *sqliteWriter) Write(t *suger.Title) {
+	id := t.Key()
+	var maxRating sql.NullString
+	if r, ok := t.MaxRating(); ok {
+		maxRating = sql.NullString{String: r, Valid: true}
+	}
+	_, err := sw.tx.Exec(sqliteUpsert, id, t.Name, t.URL, t.Distributor, t.RunningTime, maxRating)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// replace the title's ratings, rather than adding to them
+	_, err = sw.tx.Exec("DELETE FROM ratings WHERE title_id = ?", id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, r := range t.Ratings {
+		_, err = sw.tx.Exec("INSERT INTO ratings (title_id, rating, decision) VALUES (?, ?, ?)", id, r.Rating, r.Decision)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (sw *sqliteWriter) Close() {
+	err := sw.tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = sw.db.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
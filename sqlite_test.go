@@ -0,0 +1,73 @@
+package main
+
+import (
+	suger "github.com/colinhb/suger/libsuger"
+	"testing"
+)
+
+func TestSQLiteWriter(t *testing.T) {
+	sw := newSQLiteWriter(":memory:")
+	defer sw.Close()
+	sw.Write(&suger.Title{ID: "ID1", Name: "OLD NAME", URL: "u1", Ratings: []suger.Rating{{Rating: "Parental Guidance", Decision: "Passed Clean"}}})
+	sw.Write(&suger.Title{ID: "ID2", Name: "REFUSED", URL: "u2", Ratings: []suger.Rating{{Rating: "", Decision: "Not for All Ratings"}}})
+	// written again, as by a later scrape: updated, not duplicated
+	sw.Write(&suger.Title{ID: "ID1", Name: "NEW NAME", URL: "u1", Distributor: "FAKE PICTURES", RunningTime: 90, Ratings: []suger.Rating{
+		{Rating: "Parental Guidance", Decision: "Passed Clean"},
+		{Rating: "No Children Under 16", Decision: "Passed With Cuts"},
+	}})
+
+	// an in-memory database is only seen by the writer's own
+	// connection, so query it within its transaction
+	var titles int
+	err := sw.tx.QueryRow("SELECT count(*) FROM titles").Scan(&titles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if titles != 2 {
+		t.Errorf("%v titles, want 2", titles)
+	}
+	var name, distributor, maxRating string
+	var runningTime int
+	err = sw.tx.QueryRow("SELECT name, distributor, running_time, max_rating FROM titles WHERE id = ?", "ID1").Scan(&name, &distributor, &runningTime, &maxRating)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "NEW NAME" || distributor != "FAKE PICTURES" || runningTime != 90 || maxRating != "No Children Under 16" {
+		t.Errorf("ID1 is %q, %q, %v, %q, want the second one written", name, distributor, runningTime, maxRating)
+	}
+	rows, err := sw.tx.Query("SELECT rating, decision FROM ratings WHERE title_id = ? ORDER BY rowid", "ID1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ratings []string
+	for rows.Next() {
+		var rating, decision string
+		err = rows.Scan(&rating, &decision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ratings = append(ratings, rating+": "+decision)
+	}
+	err = rows.Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	want := []string{"Parental Guidance: Passed Clean", "No Children Under 16: Passed With Cuts"}
+	if !equalStrings(ratings, want) {
+		t.Errorf("ID1 has ratings %q, want %q", ratings, want)
+	}
+	var refusedMax *string
+	err = sw.tx.QueryRow("SELECT max_rating FROM titles WHERE id = ?", "ID2").Scan(&refusedMax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refusedMax != nil {
+		t.Errorf("ID2 has max_rating %q, want NULL", *refusedMax)
+	}
+	// foreign keys are enforced on the writer's connection
+	_, err = sw.tx.Exec("INSERT INTO ratings (title_id, rating, decision) VALUES (?, ?, ?)", "NO SUCH ID", "Parental Guidance", "Passed Clean")
+	if err == nil {
+		t.Errorf("a rating for a title that doesn't exist was inserted")
+	}
+}